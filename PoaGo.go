@@ -2,11 +2,18 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
 	"runtime/pprof"
+	"strings"
 
 	PoaGo "github.com/ArtRand/PoaGo/lib"
 )
@@ -19,28 +26,408 @@ func check(ok error, msg string) {
 	}
 }
 
-const (
+// set at build time with -ldflags "-X main.VERSION=... -X main.REVISION=...", see the Makefile
+var (
 	VERSION  = "NOTSET"
 	REVISION = "NOTSET"
 )
 
-func main() {
-	inFile := flag.String("f", "", "file location")
-	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
-	version := flag.Bool("version", false, "print the version and revision")
+// the version and revision, taken from the build info when they weren't set at build time
+func buildVersion() (string, string) {
+	version, revision := VERSION, REVISION
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, revision
+	}
+	if version == "NOTSET" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && revision == "NOTSET" {
+			revision = setting.Value
+			if len(revision) > 7 {
+				revision = revision[:7]
+			}
+		}
+	}
+	return version, revision
+}
 
-	flag.Parse()
+// version: the version, revision and how the binary was built
+func runVersion(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	version, revision := buildVersion()
+	fmt.Printf("Version: %s\n", version)
+	fmt.Printf("Revision: %s\n", revision)
+	fmt.Printf("Go: %s\n", runtime.Version())
+	fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	fmt.Printf("Module: %s %s\n", info.Main.Path, info.Main.Version)
+	if len(info.Settings) > 0 {
+		fmt.Printf("Build settings:\n")
+		for _, setting := range info.Settings {
+			fmt.Printf("  %s=%s\n", setting.Key, setting.Value)
+		}
+	}
+}
 
-	if *version {
-		fmt.Printf("Version: %s", VERSION)
-		fmt.Printf("Revision: %s", REVISION)
-		os.Exit(0)
+// writes one fasta file per consensus cluster containing the reads assigned to it
+func writeClusters(g *PoaGo.PoaGraph, prefix string, maxFraction float64, minSupport int) {
+	clusters, unassigned := g.HaplotypeClusters(maxFraction, minSupport)
+	for i, cluster := range clusters {
+		fileName := fmt.Sprintf("%s.cluster%d.fa", prefix, i)
+		fH, ok := os.Create(fileName)
+		check(ok, fmt.Sprintf("Error creating file %v", fileName))
+		for _, label := range cluster.Labels {
+			seq, _ := g.Sequence(label)
+			if g.IsReversed(label) {
+				seq = PoaGo.ReverseComplement(seq)
+			}
+			check(PoaGo.WriteFasta(fH, label, seq), fmt.Sprintf("Error writing to %v", fileName))
+		}
+		fH.Close()
+		fmt.Fprintf(os.Stderr, "Cluster %d: %d reads -> %v\n", i, cluster.Support, fileName)
+	}
+	if len(unassigned) > 0 {
+		fmt.Fprintf(os.Stderr, "%d reads not assigned to a cluster\n", len(unassigned))
+	}
+}
+
+// inputFiles : the -f flag can be given more than once
+type inputFiles []string
+
+func (self *inputFiles) String() string {
+	return strings.Join(*self, ",")
+}
+
+func (self *inputFiles) Set(value string) error {
+	*self = append(*self, value)
+	return nil
+}
+
+// name of the profile, the input file name without directory and extensions
+func profileName(inFile string) string {
+	if inFile == "-" {
+		return "stdin"
+	}
+	name := filepath.Base(inFile)
+	for _, ext := range []string{".gz", ".bz2", ".xz", ".zst"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+func writeProfile(g *PoaGo.PoaGraph, fileName, format string, opts PoaGo.ProfileOptions, matchGaps float64, name string) {
+	p := g.BuildProfile(name, opts)
+	fH, ok := os.Create(fileName)
+	check(ok, fmt.Sprintf("Error creating file %v", fileName))
+	defer fH.Close()
+
+	switch format {
+	case "tsv":
+		ok = p.WritePfmTSV(fH)
+	case "jaspar":
+		ok = p.WriteJaspar(fH)
+	case "meme":
+		ok = p.WriteMeme(fH)
+	case "hmm":
+		ok = p.WriteHmmer3(fH, matchGaps)
+	default:
+		ok = fmt.Errorf("unknown profile format %v", format)
+	}
+	check(ok, fmt.Sprintf("Error writing profile %v: %v", fileName, ok))
+}
+
+func writeIdentityMatrix(g *PoaGo.PoaGraph, fileName, format string) {
+	fH, ok := os.Create(fileName)
+	check(ok, fmt.Sprintf("Error creating file %v", fileName))
+	defer fH.Close()
+
+	switch format {
+	case "tsv":
+		names, identity := g.IdentityMatrix()
+		ok = PoaGo.WriteMatrixTSV(fH, names, identity)
+	case "phylip":
+		names, distance := g.DistanceMatrix()
+		ok = PoaGo.WritePhylipDistance(fH, names, distance)
+	default:
+		ok = fmt.Errorf("unknown identity matrix format %v", format)
+	}
+	check(ok, fmt.Sprintf("Error writing identity matrix %v: %v", fileName, ok))
+}
+
+func loadMSA(fileName, format string) *PoaGo.PoaGraph {
+	fH, ok := PoaGo.OpenInput(fileName)
+	check(ok, fmt.Sprintf("Error opening alignment %v: %v", fileName, ok))
+	defer fH.Close()
+
+	msa, ok := PoaGo.ReadMSA(fH, format)
+	check(ok, fmt.Sprintf("Error reading alignment %v: %v", fileName, ok))
+	g, ok := PoaGo.PoaGraphFromMSA(msa)
+	check(ok, fmt.Sprintf("Error building graph from %v: %v", fileName, ok))
+	return g
+}
+
+func writeHTMLReport(g *PoaGo.PoaGraph, gf *graphFlags, fileName, scheme string) {
+	opts := PoaGo.ReportOptionsDefault()
+	colorScheme, ok := PoaGo.ParseColorScheme(scheme)
+	check(ok, fmt.Sprintf("%v", ok))
+	opts.Title = fmt.Sprintf("PoaGo alignment of %v", gf.inputName())
+	opts.ReadsUsed, opts.ReadsSkipped = gf.nbUsed, gf.nbSkipped
+	opts.Scheme, opts.MaxFraction = colorScheme, *gf.maxFraction
+
+	fH, ok := os.Create(fileName)
+	check(ok, fmt.Sprintf("Error creating file %v", fileName))
+	defer fH.Close()
+	check(g.WriteHTMLReport(fH, opts), fmt.Sprintf("Error writing report %v", fileName))
+}
+
+// output file, - for stdout
+func createOutput(fileName string) io.WriteCloser {
+	if fileName == "-" || fileName == "" {
+		return nopWriteCloser{os.Stdout}
+	}
+	fH, ok := os.Create(fileName)
+	check(ok, fmt.Sprintf("Error creating file %v", fileName))
+	return fH
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// flags of the commands that build a graph from the input
+type graphFlags struct {
+	inFiles     inputFiles
+	orient      *string
+	maxFraction *float64
+	msaFile     *string
+	msaFormat   *string
+	match       *float64
+	mismatch    *float64
+	gapOpen     *float64
+	gapExtend   *float64
+	mode        *string
+	threads     *int
+	batch       *int
+	output      *string
+	config      *string
+	prune       *int
+
+	nbUsed, nbSkipped int // reads added to the graph and reads without an alignment
+}
+
+func addGraphFlags(fs *flag.FlagSet) *graphFlags {
+	gf := &graphFlags{}
+	fs.Var(&gf.inFiles, "f", "input fasta/fastq file, - for stdin, may be compressed and repeated")
+	gf.orient = fs.String("orient", "none", "read orientation detection: none, align or kmer")
+	gf.maxFraction = fs.Float64("max-fraction", PoaGo.DefaultMaxFraction, "fraction of a read on a consensus needed to assign it")
+	gf.msaFile = fs.String("msa", "", "start from an existing alignment, the reads are added to it")
+	gf.msaFormat = fs.String("msa-format", "auto", "format of the -msa alignment: fasta, clustal, stockholm or auto")
+	gf.match = fs.Float64("match", 4.0, "score of a match")
+	gf.mismatch = fs.Float64("mismatch", -2.0, "score of a mismatch")
+	gf.gapOpen = fs.Float64("gap-open", -4.0, "score of the first position of a gap")
+	gf.gapExtend = fs.Float64("gap-extend", -2.0, "score of the following positions of a gap")
+	gf.mode = fs.String("mode", "local", "alignment of the reads to the graph: local, global or semiglobal")
+	gf.threads = fs.Int("threads", 1, "number of goroutines aligning the reads of a batch, the output doesn't depend on it")
+	gf.batch = fs.Int("batch", 1, "align this many reads to the graph as it was before them, 1 adds each read before aligning the next")
+	gf.output = fs.String("o", "-", "output file, - for stdout")
+	gf.prune = fs.Int("prune", 0, "remove the nodes and edges supported by fewer reads once all the reads are added, rerouting the reads")
+	gf.config = fs.String("config", "", "read default options from a YAML or TOML file")
+	return gf
+}
+
+// name of the input, used to name profiles
+func (self *graphFlags) inputName() string {
+	return profileName(append(self.inFiles, *self.msaFile)[0])
+}
+
+func (self *graphFlags) alignmentParameters() *PoaGo.PairwiseAlignmentParameters {
+	mode, ok := PoaGo.ParseAlignmentMode(*self.mode)
+	check(ok, fmt.Sprintf("%v", ok))
+	aln := PoaGo.PairwiseAlignmentParametersConstruct(*self.match, *self.mismatch, *self.gapOpen, *self.gapExtend)
+	aln.SetMode(mode)
+	return aln
+}
+
+// checks the values of the flags that can't be used as they are
+func (self *graphFlags) validate() error {
+	if *self.threads < 1 {
+		return fmt.Errorf("-threads should be at least 1, got %d", *self.threads)
+	}
+	if *self.batch < 1 {
+		return fmt.Errorf("-batch should be at least 1, got %d", *self.batch)
 	}
+	return nil
+}
+
+// builds the graph from the -msa alignment and the reads of the -f files
+func (self *graphFlags) build() *PoaGo.PoaGraph {
+	orientation, ok := PoaGo.ParseOrientationMode(*self.orient)
+	check(ok, fmt.Sprintf("%v", ok))
+	aln := self.alignmentParameters()
 
-	fH, ok := os.Open(*inFile)
-	check(ok, fmt.Sprintf("Error opening file %v", *inFile))
+	if len(self.inFiles) == 0 && *self.msaFile == "" {
+		self.inFiles = append(self.inFiles, "-")
+	}
+	fH, ok := PoaGo.OpenInputs(self.inFiles)
+	check(ok, fmt.Sprintf("Error opening input %v: %v", self.inFiles, ok))
 	defer fH.Close()
 
+	fqr := PoaGo.NewFastxReader(fH)
+
+	// the graph starts from the alignment or from the first sequence
+	var g *PoaGo.PoaGraph
+	if *self.msaFile != "" {
+		g = loadMSA(*self.msaFile, *self.msaFormat)
+	}
+
+	// the description, qualities and tags of the reads go with them into the graph
+	// the batches have the same reads whatever the number of threads, so the graph doesn't depend on it
+	records := make([]PoaGo.SeqRecord, 0, *self.batch)
+	seqs, labels := make([]string, 0, *self.batch), make([]string, 0, *self.batch)
+	addBatch := func() {
+		for i, pA := range PoaGo.AlignOrientedBatch(g, aln, seqs, labels, orientation, *self.threads) {
+			if !pA.Aligned() {
+				fmt.Fprintf(os.Stderr, "Skipping %v, no alignment to the graph\n", pA.Label())
+				self.nbSkipped += 1
+				continue
+			}
+			pA.SetRecord(records[i])
+			ok := g.AddSequenceAlignment(pA)
+			var cycle *PoaGo.CycleError
+			if errors.As(ok, &cycle) {
+				// the read was taken out again, the graph is as it was
+				fmt.Fprintf(os.Stderr, "Skipping %v, its alignment closes a %v\n", pA.Label(), ok)
+				self.nbSkipped += 1
+				continue
+			}
+			check(ok, fmt.Sprintf("Error adding %v to the graph: %v", pA.Label(), ok))
+			self.nbUsed += 1
+		}
+		records, seqs, labels = records[:0], seqs[:0], labels[:0]
+	}
+
+	for {
+		r, ok := fqr.Next()
+		if ok == io.EOF {
+			break
+		}
+		check(ok, fmt.Sprintf("Error reading input %v: %v", self.inFiles, ok))
+		if g == nil {
+			g = PoaGo.PoaGraphConstruct()
+			_, _ = g.AddBaseRecord(PoaGo.SeqRecordFromFastx(r))
+			self.nbUsed += 1
+			continue
+		}
+		records = append(records, PoaGo.SeqRecordFromFastx(r))
+		seqs, labels = append(seqs, r.Seq), append(labels, r.Name)
+		if len(seqs) >= *self.batch {
+			addBatch()
+		}
+	}
+	if len(seqs) > 0 {
+		addBatch()
+	}
+
+	if g == nil {
+		fmt.Fprintln(os.Stderr, "No sequences in the input")
+		os.Exit(1)
+	}
+	if *self.prune > 1 {
+		stats := g.Prune(*self.prune)
+		fmt.Fprintf(os.Stderr, "Pruned %d nodes and %d edges supported by fewer than %d reads, %d reads rerouted, %d left out\n",
+			stats.Nodes, stats.Edges, *self.prune, stats.Rerouted, stats.Cut)
+	}
+	g.SetMaxFraction(*self.maxFraction)
+	return g
+}
+
+// true if f is a terminal rather than a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+type command struct {
+	name        string
+	description string
+	run         func(fs *flag.FlagSet, args []string)
+}
+
+var commands = []command{
+	{"align", "Align the reads and write the multiple sequence alignment. This is the default command.", runAlign},
+	{"consensus", "Align the reads and write the consensus sequences as fasta.", runConsensus},
+	{"graph", "Align the reads and write the partial order graph as GFA.", runGraph},
+	{"view", "Align the reads and show the alignment in blocks, for reading in a terminal.", runView},
+	{"call", "Align the reads and write their variants against the first consensus as VCF.", runCall},
+	{"polish", "Polish draft sequences window by window with reads mapped to them (PAF or SAM).", runPolish},
+	{"umi", "Group the reads by UMI and write the consensus of each group as fastq.", runUmi},
+	{"version", "Print the version, revision, Go version and build settings.", runVersion},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: PoaGo <command> [options]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun PoaGo <command> -h for the options of a command.\n")
+}
+
+// parses the command line and the -config file of a command, exits on invalid options
+func parseFlags(fs *flag.FlagSet, gf *graphFlags, args []string) {
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected arguments %v\n", fs.Args())
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := applyConfig(fs, *gf.config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := gf.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// align: the multiple sequence alignment and optional extra outputs
+func runAlign(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	cpuprofile := fs.String("cpuprofile", "", "write cpu profile to file")
+	version := fs.Bool("version", false, "print the version and revision")
+	minSupport := fs.Int("min-support", 1, "minimum number of reads supporting a consensus cluster")
+	columnStats := fs.String("column-stats", "", "write per column alignment statistics (TSV) to file")
+	profile := fs.String("profile", "", "write a profile of the alignment to file")
+	profileFormat := fs.String("profile-format", "tsv", "profile format: tsv, jaspar, meme or hmm")
+	pseudocount := fs.Float64("pseudocount", 0.0, "pseudocount added to the profile residue counts")
+	weighting := fs.String("weighting", "none", "profile sequence weighting: none or henikoff")
+	matchGaps := fs.Float64("match-gap-fraction", 0.5, "columns with fewer gaps become HMM match states")
+	identityMatrix := fs.String("identity-matrix", "", "write the pairwise sequence identity matrix to file")
+	identityFormat := fs.String("identity-format", "tsv", "identity matrix format: tsv (identity) or phylip (distance)")
+	clusterPrefix := fs.String("clusters", "", "write the reads of each consensus cluster to <prefix>.cluster<N>.fa")
+	format := fs.String("format", "plain", "alignment output format: plain, fasta, clustal, stockholm or sam")
+	consensus := fs.Bool("consensus", true, "write the consensus rows after the sequences")
+	htmlReport := fs.String("html", "", "write a self-contained html report of the alignment to file")
+	scheme := fs.String("scheme", "ansi", "residue colors of the html report: ansi (nucleotides), clustal, zappo or none")
+	parseFlags(fs, gf, args)
+
+	if *version {
+		version, revision := buildVersion()
+		fmt.Printf("Version: %s\n", version)
+		fmt.Printf("Revision: %s\n", revision)
+		os.Exit(0)
+	}
+
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -50,30 +437,355 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	fqr := PoaGo.FqReader{Reader: bufio.NewReader(fH)}
+	g := gf.build()
+	maxFraction := gf.maxFraction
+
+	if *clusterPrefix != "" {
+		writeClusters(g, *clusterPrefix, *maxFraction, *minSupport)
+	}
+
+	if *columnStats != "" {
+		fH, ok := os.Create(*columnStats)
+		check(ok, fmt.Sprintf("Error creating file %v", *columnStats))
+		check(PoaGo.WriteColumnStatsTSV(fH, g.ColumnStatistics()), fmt.Sprintf("Error writing to %v", *columnStats))
+		fH.Close()
+	}
+
+	if *profile != "" {
+		weights, ok := PoaGo.ParseSequenceWeighting(*weighting)
+		check(ok, fmt.Sprintf("%v", ok))
+		writeProfile(g, *profile, *profileFormat, PoaGo.ProfileOptions{Pseudocount: *pseudocount, Weighting: weights},
+			*matchGaps, gf.inputName())
+	}
+
+	if *identityMatrix != "" {
+		writeIdentityMatrix(g, *identityMatrix, *identityFormat)
+	}
+
+	if *htmlReport != "" {
+		writeHTMLReport(g, gf, *htmlReport, *scheme)
+	}
+
+	fH := createOutput(*gf.output)
+	defer fH.Close()
+	out := bufio.NewWriter(fH)
+	defer out.Flush()
+	w, ok := PoaGo.AlignmentWriterForFormat(*format, out)
+	check(ok, fmt.Sprintf("%v", ok))
+	opts := PoaGo.AlignmentOptionsDefault()
+	opts.Consensus = *consensus
+	opts.MaxFraction = *maxFraction
+	check(g.WriteAlignment(w, opts), "Error writing the alignment")
+}
 
-	// get the first sequence
-	r, done := fqr.Iter()
-	// add it to the graph
-	g := PoaGo.PoaGraphConstruct()
-	_, _ = g.AddBaseSequence(r.Seq, r.Name, true)
+// consensus: one fasta record per consensus with the number of reads supporting it
+func runConsensus(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	minSupport := fs.Int("min-support", 1, "minimum number of reads supporting a consensus")
+	clusterPrefix := fs.String("clusters", "", "write the reads of each consensus cluster to <prefix>.cluster<N>.fa")
+	polish := fs.Int("polish", 0, "polish each consensus with up to this many rounds of realigning its reads to it")
+	parseFlags(fs, gf, args)
 
-	aln := PoaGo.PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g := gf.build()
+	if *clusterPrefix != "" {
+		writeClusters(g, *clusterPrefix, *gf.maxFraction, *minSupport)
+	}
 
+	fH := createOutput(*gf.output)
+	defer fH.Close()
+	out := bufio.NewWriter(fH)
+	defer out.Flush()
+	clusters, _ := g.HaplotypeClusters(*gf.maxFraction, *minSupport)
+	for i, cluster := range clusters {
+		name := fmt.Sprintf("Consensus%d support=%d", i, cluster.Support)
+		seq := cluster.Sequence()
+		if *polish > 0 {
+			seq = polishCluster(g, cluster, gf.alignmentParameters(), *polish, fmt.Sprintf("Consensus%d", i))
+		}
+		check(PoaGo.WriteFasta(out, name, seq), "Error writing the consensus")
+	}
+}
+
+// polishes the consensus of a cluster with the reads assigned to it, the rounds are reported on stderr
+func polishCluster(g *PoaGo.PoaGraph, cluster *PoaGo.ConsensusCluster, aln *PoaGo.PairwiseAlignmentParameters,
+	maxRounds int, name string) string {
+	assigned := make(map[string]bool)
+	for _, label := range cluster.Labels {
+		assigned[label] = true
+	}
+	records := make([]*PoaGo.SeqRecord, 0)
+	for _, rec := range g.Records() {
+		if assigned[rec.Name] {
+			records = append(records, rec)
+		}
+	}
+	opts := PoaGo.PolishOptionsDefault()
+	opts.MaxRounds = maxRounds
+	result, err := PoaGo.PolishSequences(cluster.Sequence(), records, aln, opts, g.MaxFraction())
+	check(err, fmt.Sprintf("Error polishing %v: %v", name, err))
+	for i, round := range result.Rounds {
+		fmt.Fprintf(os.Stderr, "%v polishing round %d: %d reads aligned, %d edits\n", name, i+1, round.NbAligned, round.EditDistance)
+	}
+	if result.Cycling {
+		fmt.Fprintf(os.Stderr, "%v goes back to an earlier consensus, stopped after %d rounds\n", name, len(result.Rounds))
+	} else if !result.Converged {
+		fmt.Fprintf(os.Stderr, "%v didn't converge after %d rounds\n", name, maxRounds)
+	}
+	return result.Consensus
+}
+
+// graph: the partial order graph
+func runGraph(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	format := fs.String("format", "gfa", "graph output format: gfa")
+	compact := fs.Bool("compact", false, "merge unbranched chains of nodes into one segment")
+	parseFlags(fs, gf, args)
+	if *format != "gfa" {
+		check(fmt.Errorf("unknown graph format %v", *format), fmt.Sprintf("Unknown graph format %v, should be gfa", *format))
+	}
+
+	g := gf.build()
+	fH := createOutput(*gf.output)
+	defer fH.Close()
+	if *compact {
+		check(g.Compact().WriteGFA(fH), "Error writing the graph")
+		return
+	}
+	check(g.WriteGFA(fH), "Error writing the graph")
+}
+
+// view: the alignment in blocks for reading in a terminal
+func runView(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	width := fs.Int("width", 60, "number of columns per block")
+	scheme := fs.String("scheme", "ansi", "residue colors: ansi (nucleotides), clustal, zappo or none")
+	color := fs.String("color", "auto", "use colors: auto (when writing to a terminal), always or never")
+	highlight := fs.Bool("highlight", true, "mark the residues that differ from the consensus")
+	ruler := fs.Bool("ruler", true, "show column numbers above each block")
+	consensus := fs.Bool("consensus", true, "show the consensus rows")
+	parseFlags(fs, gf, args)
+
+	g := gf.build()
+
+	opts := PoaGo.ViewOptionsDefault()
+	colorScheme, ok := PoaGo.ParseColorScheme(*scheme)
+	check(ok, fmt.Sprintf("%v", ok))
+	opts.Width, opts.Scheme, opts.Highlight, opts.Ruler = *width, colorScheme, *highlight, *ruler
+	switch *color {
+	case "auto":
+		opts.Color = (*gf.output == "-" || *gf.output == "") && isTerminal(os.Stdout)
+	case "always":
+		opts.Color = true
+	case "never":
+		opts.Color = false
+	default:
+		check(fmt.Errorf("unknown -color %v", *color), fmt.Sprintf("Unknown -color %v, should be auto, always or never", *color))
+	}
+
+	fH := createOutput(*gf.output)
+	defer fH.Close()
+	out := bufio.NewWriter(fH)
+	defer out.Flush()
+	alnOpts := PoaGo.AlignmentOptionsDefault()
+	alnOpts.Consensus = *consensus
+	alnOpts.MaxFraction = *gf.maxFraction
+	check(g.WriteAlignment(PoaGo.AlignmentViewerConstruct(out, opts), alnOpts), "Error writing the alignment")
+}
+
+// call: variants of the reads against the first consensus
+func runCall(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	minCount := fs.Int("min-count", 1, "minimum number of reads with an alternative allele")
+	parseFlags(fs, gf, args)
+
+	g := gf.build()
+	opts := PoaGo.VariantOptionsDefault()
+	opts.MaxFraction, opts.MinCount = *gf.maxFraction, *minCount
+	calls, ok := g.CallVariants(opts)
+	check(ok, fmt.Sprintf("Error calling variants: %v", ok))
+
+	fH := createOutput(*gf.output)
+	defer fH.Close()
+	check(calls.WriteVCF(fH), "Error writing the variants")
+}
+
+// umi: one fastq record per group of reads sharing a UMI, with the UMI and the size of the group
+func runUmi(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	pattern := fs.String("umi-pattern", PoaGo.DefaultUmiPattern, "regular expression matching the UMI, its first group if it has one")
+	source := fs.String("umi-from", "name", "where the UMI is: name, or seq to find it in the read and trim the match")
+	maxEdits := fs.Int("umi-edits", 1, "UMIs at most this many edits apart are grouped")
+	minGroupSize := fs.Int("min-group-size", 3, "minimum number of reads in a group to write its consensus")
+	parseFlags(fs, gf, args)
+	if *gf.msaFile != "" {
+		fmt.Fprintln(os.Stderr, "umi doesn't take -msa, each group is aligned on its own")
+		os.Exit(2)
+	}
+
+	opts := PoaGo.UmiOptionsDefault()
+	re, ok := regexp.Compile(*pattern)
+	check(ok, fmt.Sprintf("Error in the UMI pattern %v: %v", *pattern, ok))
+	opts.Pattern, opts.MaxEdits, opts.MinGroupSize = re, *maxEdits, *minGroupSize
+	opts.Source, ok = PoaGo.ParseUmiSource(*source)
+	check(ok, fmt.Sprintf("%v", ok))
+	orientation, ok := PoaGo.ParseOrientationMode(*gf.orient)
+	check(ok, fmt.Sprintf("%v", ok))
+
+	if len(gf.inFiles) == 0 {
+		gf.inFiles = append(gf.inFiles, "-")
+	}
+	fH, ok := PoaGo.OpenInputs(gf.inFiles)
+	check(ok, fmt.Sprintf("Error opening input %v: %v", gf.inFiles, ok))
+	defer fH.Close()
+	fqr := PoaGo.NewFastxReader(fH)
+	records, umis := make([]PoaGo.SeqRecord, 0), make([]string, 0)
+	nbNoUmi := 0
+	for {
+		r, ok := fqr.Next()
+		if ok == io.EOF {
+			break
+		}
+		check(ok, fmt.Sprintf("Error reading input %v: %v", gf.inFiles, ok))
+		umi, rec, found := PoaGo.ExtractUmi(PoaGo.SeqRecordFromFastx(r), opts)
+		if !found {
+			nbNoUmi += 1
+			continue
+		}
+		records, umis = append(records, rec), append(umis, umi)
+	}
+
+	groups := make([]*PoaGo.UmiGroup, 0)
+	nbSmall := 0
+	for _, group := range PoaGo.GroupByUmi(records, umis, opts.MaxEdits) {
+		if len(group.Records) < opts.MinGroupSize {
+			nbSmall += 1
+			continue
+		}
+		groups = append(groups, group)
+	}
+	fmt.Fprintf(os.Stderr, "%d reads without a UMI, %d groups, %d with fewer than %d reads\n",
+		nbNoUmi, len(groups)+nbSmall, nbSmall, opts.MinGroupSize)
+	ok = PoaGo.ConsensusUmiGroups(groups, gf.alignmentParameters(), orientation, *gf.threads)
+	check(ok, fmt.Sprintf("Error computing the consensus: %v", ok))
+
+	out := createOutput(*gf.output)
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	for i, group := range groups {
+		description := fmt.Sprintf("umi=%s size=%d", group.Umi, len(group.Records))
+		check(PoaGo.WriteFastq(w, fmt.Sprintf("Umi%d", i), description, group.Seq, group.Qual), "Error writing the consensus")
+	}
+}
+
+// reads all the records of a fasta/fastq input
+func readRecords(fileNames []string) []PoaGo.SeqRecord {
+	fH, ok := PoaGo.OpenInputs(fileNames)
+	check(ok, fmt.Sprintf("Error opening input %v: %v", fileNames, ok))
+	defer fH.Close()
+	fqr := PoaGo.NewFastxReader(fH)
+	records := make([]PoaGo.SeqRecord, 0)
 	for {
-		r, done = fqr.Iter()
-		if done {
+		r, ok := fqr.Next()
+		if ok == io.EOF {
 			break
 		}
-		pA := PoaGo.AlignStringToGraph(g, aln, r.Seq, r.Name)
-		g.AddSequenceAlignment(pA)
+		check(ok, fmt.Sprintf("Error reading input %v: %v", fileNames, ok))
+		records = append(records, PoaGo.SeqRecordFromFastx(r))
+	}
+	return records
+}
+
+// polish: each draft sequence polished with the reads mapped to it, a window at a time
+func runPolish(fs *flag.FlagSet, args []string) {
+	gf := addGraphFlags(fs)
+	targetFile := fs.String("target", "", "fasta file with the draft sequences")
+	mappingFile := fs.String("mappings", "", "PAF or SAM file with the reads mapped to the draft sequences")
+	mappingFormat := fs.String("mappings-format", "auto", "format of the -mappings: paf, sam or auto from the file name")
+	windowLength := fs.Int("window", 500, "length of the windows the draft sequences are cut into")
+	minCoverage := fs.Int("min-coverage", 3, "minimum number of reads in a window to polish it")
+	parseFlags(fs, gf, args)
+	format := strings.ToLower(*mappingFormat)
+	if format == "auto" {
+		format = "paf"
+		if name := strings.TrimSuffix(*mappingFile, ".gz"); strings.HasSuffix(name, ".sam") {
+			format = "sam"
+		}
+	}
+	if format != "paf" && format != "sam" {
+		fmt.Fprintf(os.Stderr, "Unknown mappings format %v, should be paf, sam or auto\n", *mappingFormat)
+		os.Exit(2)
+	}
+	if *targetFile == "" || *mappingFile == "" || (len(gf.inFiles) == 0 && format == "paf") {
+		fmt.Fprintln(os.Stderr, "polish needs the draft sequences -target, their -mappings and the reads -f unless they are in the SAM file")
+		os.Exit(2)
 	}
 
-	seqNames, alnStrings := g.GenerateAlignmentStrings()
+	mH, ok := PoaGo.OpenInput(*mappingFile)
+	check(ok, fmt.Sprintf("Error opening %v: %v", *mappingFile, ok))
+	var mappings []PoaGo.ReadMapping
+	var records []PoaGo.SeqRecord
+	if format == "sam" {
+		mappings, records, ok = PoaGo.ReadSAM(mH)
+	} else {
+		mappings, ok = PoaGo.ReadPAF(mH)
+	}
+	mH.Close()
+	check(ok, fmt.Sprintf("Error reading %v: %v", *mappingFile, ok))
+	if len(gf.inFiles) > 0 {
+		records = readRecords(gf.inFiles)
+	}
+	reads := make(map[string]PoaGo.SeqRecord)
+	for _, rec := range records {
+		reads[rec.Name] = rec
+	}
 
-	for i := 0; i < len(seqNames); i++ {
-		fmt.Printf("%-12s\t%-6s\n", seqNames[i], alnStrings[i])
+	opts := PoaGo.WindowOptionsDefault()
+	opts.WindowLength, opts.MinCoverage, opts.Threads = *windowLength, *minCoverage, *gf.threads
+	aln := gf.alignmentParameters()
+	out := createOutput(*gf.output)
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	for _, target := range readRecords([]string{*targetFile}) {
+		polished, ok := PoaGo.PolishWindows(target, reads, mappings, aln, opts)
+		check(ok, fmt.Sprintf("Error polishing %v: %v", target.Name, ok))
+		fmt.Fprintf(os.Stderr, "%v: %d of %d windows polished\n", target.Name, polished.NbPolished, polished.NbWindows)
+		check(PoaGo.WriteFasta(w, polished.Name, polished.Seq), "Error writing the polished sequence")
+	}
+}
+
+func main() {
+	PoaGo.Program.Version, PoaGo.Program.Revision = buildVersion()
+	PoaGo.Program.CommandLine = strings.Join(os.Args, " ")
+
+	args := os.Args[1:]
+	name := "align"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		if len(args) == 0 {
+			usage()
+			return
+		}
+		name, args = args[0], []string{"-h"}
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: PoaGo %s [options]\n\n%s\n\nOptions:\n", c.name, c.description)
+			fs.PrintDefaults()
+		}
+		c.run(fs, args)
+		return
 	}
 
-	return
+	fmt.Fprintf(os.Stderr, "Unknown command %v\n\n", name)
+	usage()
+	os.Exit(2)
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// reads a YAML (.yaml, .yml) or TOML (.toml) config file. Keys are flag names without the dash,
+// top-level keys apply to every command and a table named after a command only to that command:
+//
+//	mode: semiglobal
+//	threads: 4
+//	view:
+//	  width: 100
+func readConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	case ".toml":
+		err = toml.Unmarshal(data, &config)
+	default:
+		err = fmt.Errorf("unknown config format %v, should be .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %v: %v", path, err)
+	}
+	return config, nil
+}
+
+// sets a flag from a config value, lists are set one element at a time for repeatable flags
+func setFlagFromConfig(fs *flag.FlagSet, name string, value interface{}) error {
+	if values, ok := value.([]interface{}); ok {
+		for _, v := range values {
+			if err := fs.Set(name, fmt.Sprint(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fs.Set(name, fmt.Sprint(value))
+}
+
+// applyConfig sets the flags of a command that weren't given on the command line from the -config
+// file. Unknown keys in the command's own table are errors, unknown top-level keys are ignored as
+// they can be meant for other commands
+func applyConfig(fs *flag.FlagSet, path string) error {
+	if path == "" {
+		return nil
+	}
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	apply := func(name string, value interface{}) error {
+		if given[name] {
+			return nil
+		}
+		if err := setFlagFromConfig(fs, name, value); err != nil {
+			return fmt.Errorf("config %v: %v: %v", path, name, err)
+		}
+		return nil
+	}
+
+	for name, value := range config {
+		if _, isTable := value.(map[string]interface{}); isTable || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := apply(name, value); err != nil {
+			return err
+		}
+	}
+	// the command's table overrides the top-level keys
+	if section, ok := config[fs.Name()].(map[string]interface{}); ok {
+		for name, value := range section {
+			if fs.Lookup(name) == nil {
+				return fmt.Errorf("config %v: unknown option %v for %v", path, name, fs.Name())
+			}
+			if err := apply(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
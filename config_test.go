@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a command with the graph flags and one of its own, parsed with the config file written to a
+// temporary file
+func parseWithConfig(t *testing.T, command string, args []string, fileName, config string) (*graphFlags, *int, error) {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	gf := addGraphFlags(fs)
+	width := fs.Int("width", 60, "columns per block")
+	path := filepath.Join(t.TempDir(), fileName)
+	assert.Nil(t, os.WriteFile(path, []byte(config), 0644))
+	assert.Nil(t, fs.Parse(append(args, "-config", path)))
+	return gf, width, applyConfig(fs, *gf.config)
+}
+
+func TestApplyConfig(t *testing.T) {
+	yamlConfig := "mode: global\nthreads: 4\nf: [a.fa, b.fa]\nunknown: 1\nview:\n  mode: semiglobal\n  width: 100\n"
+	tomlConfig := "mode = \"global\"\nthreads = 4\nf = [\"a.fa\", \"b.fa\"]\nunknown = 1\n[view]\nmode = \"semiglobal\"\nwidth = 100\n"
+	for _, test := range []struct {
+		command string
+		args    []string
+		mode    string
+		threads int
+		width   int
+		inFiles []string
+	}{
+		// top-level keys apply to every command, the command's table overrides them
+		{"align", nil, "global", 4, 60, []string{"a.fa", "b.fa"}},
+		{"view", nil, "semiglobal", 4, 100, []string{"a.fa", "b.fa"}},
+		// the command line wins over both
+		{"align", []string{"-threads", "2", "-mode", "local"}, "local", 2, 60, []string{"a.fa", "b.fa"}},
+		{"view", []string{"-mode", "local", "-width", "80"}, "local", 4, 80, []string{"a.fa", "b.fa"}},
+		{"view", []string{"-f", "c.fa"}, "semiglobal", 4, 100, []string{"c.fa"}},
+	} {
+		for fileName, config := range map[string]string{"config.yaml": yamlConfig, "config.toml": tomlConfig} {
+			gf, width, err := parseWithConfig(t, test.command, test.args, fileName, config)
+			assert.Nil(t, err, fileName)
+			assert.Equal(t, test.mode, *gf.mode, "%v %v %v", fileName, test.command, test.args)
+			assert.Equal(t, test.threads, *gf.threads, "%v %v %v", fileName, test.command, test.args)
+			assert.Equal(t, test.width, *width, "%v %v %v", fileName, test.command, test.args)
+			assert.Equal(t, test.inFiles, []string(gf.inFiles), "%v %v %v", fileName, test.command, test.args)
+		}
+	}
+}
+
+func TestApplyConfig_Errors(t *testing.T) {
+	for fileName, config := range map[string]string{
+		"unknown.yaml": "view:\n  unknown: 1\n",
+		"value.yaml":   "threads: four\n",
+		"syntax.toml":  "threads = \n",
+		"config.ini":   "threads=4\n",
+	} {
+		_, _, err := parseWithConfig(t, "view", nil, fileName, config)
+		assert.NotNil(t, err, fileName)
+	}
+}
+
+func TestGraphFlags_validate(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		config string
+		valid  bool
+	}{
+		{nil, "", true},
+		{[]string{"-threads", "8"}, "", true},
+		{[]string{"-threads", "0"}, "", false},
+		{[]string{"-threads", "-2"}, "", false},
+		{nil, "threads: 0\n", false},
+		{[]string{"-batch", "0"}, "", false},
+		{[]string{"-batch", "16", "-threads", "4"}, "", true},
+		{[]string{"-threads", "2"}, "threads: 0\n", true},
+	} {
+		gf, _, err := parseWithConfig(t, "align", test.args, "config.yaml", test.config)
+		assert.Nil(t, err)
+		assert.Equal(t, test.valid, gf.validate() == nil, "%v %v", test.args, test.config)
+	}
+}
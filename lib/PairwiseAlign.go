@@ -140,17 +140,48 @@ func MaxMoveOption(options []*MoveOption) (*MoveOption, error) {
 	return options[bestIdx], ok
 }
 
+// AlignmentMode : which parts of the sequence and the graph have to be aligned
+type AlignmentMode int
+
+const (
+	AlignLocal      AlignmentMode = iota // best scoring part of the sequence to any part of the graph
+	AlignGlobal                          // the whole sequence from a source to a sink of the graph
+	AlignSemiGlobal                      // the whole sequence to any part of the graph
+)
+
+func ParseAlignmentMode(mode string) (AlignmentMode, error) {
+	switch mode {
+	case "local":
+		return AlignLocal, nil
+	case "global":
+		return AlignGlobal, nil
+	case "semiglobal":
+		return AlignSemiGlobal, nil
+	default:
+		return AlignLocal, errors.New(fmt.Sprintf("Unknown alignment mode %v, should be local, global or semiglobal", mode))
+	}
+}
+
 type PairwiseAlignmentParameters struct {
 	matchScore     float64
 	mismatchScore  float64
 	openGapScore   float64
 	extendGapScore float64
+	mode           AlignmentMode
 }
 
 func PairwiseAlignmentParametersConstruct(matchScore, mismatchScore, openGapScore, extendGapScore float64) *PairwiseAlignmentParameters {
 	return &PairwiseAlignmentParameters{matchScore: matchScore, mismatchScore: mismatchScore, openGapScore: openGapScore, extendGapScore: extendGapScore}
 }
 
+func (self *PairwiseAlignmentParameters) SetMode(mode AlignmentMode) {
+	self.mode = mode
+}
+
+func (self *PairwiseAlignmentParameters) Mode() AlignmentMode {
+	return self.mode
+}
+
 func (self *PairwiseAlignmentParameters) MatchBases(c1, c2 string) float64 {
 	if c1 == c2 {
 		return self.matchScore
@@ -164,52 +195,93 @@ type PairwiseAlignment struct {
 	matches    []int
 	sequence   string
 	label      string
+	score      float64
+	reverse    bool       // sequence is the reverse complement of the original read
+	record     *SeqRecord // metadata of the sequence, see SetRecord
 }
 
 func PairwiseAlignmentConstruct(strIdxs, matches []int, sequence, label string) *PairwiseAlignment {
 	return &PairwiseAlignment{stringIdxs: strIdxs, matches: matches, sequence: sequence, label: label}
 }
 
-func MakeNodeIndexMaps(g *PoaGraph) (map[int]int, map[int]int) {
-	IdToIndex := make(map[int]int)
-	IndexToId := make(map[int]int)
+func (self *PairwiseAlignment) Score() float64 {
+	return self.score
+}
 
-	if g.needSort {
-		g.TopoSort()
-		g.testSort()
-	}
+// SetRecord attaches the metadata of the read to the alignment, AddSequenceAlignment stores it in the
+// graph. The sequence and orientation are the ones of the alignment
+func (self *PairwiseAlignment) SetRecord(rec SeqRecord) {
+	self.record = &rec
+}
 
-	for i, n := range g.nodeList {
-		node := g.nodeDict[n]
-		IdToIndex[node.id] = i
-		IndexToId[i] = node.id
-	}
+func (self *PairwiseAlignment) Label() string {
+	return self.label
+}
 
-	return IdToIndex, IndexToId
+func (self *PairwiseAlignment) Reverse() bool {
+	return self.reverse
 }
 
-func prevIndices(n *Node, IdtoIndex map[int]int) []int {
-	prev := make([]int, 0)
-	for k := range n.inEdges {
-		prev = append(prev, IdtoIndex[k])
+// Aligned returns true if at least one base of the sequence is aligned to the graph, AddSequenceAlignment
+// can't handle alignments without any
+func (self *PairwiseAlignment) Aligned() bool {
+	for _, si := range self.stringIdxs {
+		if si >= 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	if len(prev) == 0 {
-		prev = append(prev, -1)
+// DoTraceBack follows the best local alignment back, rankToId is the topological order of the node ids
+func DoTraceBack(scores, backSeqMatrix, backGrphMatrix *DpMatrix, rankToId []int) ([]int, []int) {
+	besti, bestj, _ := scores.WhereMax()
+	return traceBackFrom(besti, bestj, scores, backSeqMatrix, backGrphMatrix, rankToId, AlignLocal)
+}
+
+// cell of the score matrix the traceback starts from: the best score anywhere for local alignments,
+// the best score at the end of the sequence otherwise, restricted to the sinks of the graph for global
+func traceBackStart(g *PoaGraph, scores *DpMatrix, mode AlignmentMode) (int, int, float64) {
+	if mode == AlignLocal {
+		return scores.WhereMax()
 	}
-	return prev
+	lastSeq := scores.lX - 1
+	bestj, best := 0, math.Inf(-1)
+	for i, nodeIdx := range g.nodeList {
+		if mode == AlignGlobal && g.nodes[nodeIdx].OutDegree() > 0 {
+			continue
+		}
+		if score := scores.GetValue(lastSeq, i+1); score > best {
+			bestj, best = i+1, score
+		}
+	}
+	return lastSeq, bestj, best
 }
 
-func DoTraceBack(scores, backSeqMatrix, backGrphMatrix *DpMatrix, IndexToId map[int]int) ([]int, []int) {
-	besti, bestj, _ := scores.WhereMax()
+func traceBackFrom(besti, bestj int, scores, backSeqMatrix, backGrphMatrix *DpMatrix, rankToId []int, mode AlignmentMode) ([]int, []int) {
 	matches := make([]int, 0)
 	strIndexs := make([]int, 0)
 
-	for (scores.GetValue(besti, bestj) > 0) && !(bestj == 0 && besti == 0) {
+	more := func() bool {
+		switch mode {
+		case AlignGlobal:
+			return !(bestj == 0 && besti == 0)
+		case AlignSemiGlobal:
+			// leading graph nodes are free, stop at the start of the sequence
+			return besti > 0
+		default:
+			return (scores.GetValue(besti, bestj) > 0) && !(bestj == 0 && besti == 0)
+		}
+	}
+
+	for more() {
 		nexti := int(backGrphMatrix.GetValue(besti, bestj))
 		nextj := int(backSeqMatrix.GetValue(besti, bestj))
 		curStrIdx := besti - 1
-		curNodeIdx := IndexToId[bestj-1]
+		curNodeIdx := -1 // column 0 is before the first node
+		if bestj > 0 {
+			curNodeIdx = rankToId[bestj-1]
+		}
 		if nextj != besti {
 			strIndexs = append(strIndexs, curStrIdx)
 		} else {
@@ -230,7 +302,7 @@ func DoTraceBack(scores, backSeqMatrix, backGrphMatrix *DpMatrix, IndexToId map[
 }
 
 func AlignStringToGraph(g *PoaGraph, aln *PairwiseAlignmentParameters, sequence, label string) *PairwiseAlignment {
-	IdToIndex, IndexToId := MakeNodeIndexMaps(g)
+	g.ensureSorted()
 
 	lX := len(sequence)
 	lY := g.nbNodes
@@ -241,16 +313,40 @@ func AlignStringToGraph(g *PoaGraph, aln *PairwiseAlignmentParameters, sequence,
 	insertCostMatrix := DpMatrixConstructFull(lX+1, lY+1, aln.openGapScore)
 	deleteCostMatrix := DpMatrixConstructFull(lX+1, lY+1, aln.openGapScore)
 
+	if aln.mode != AlignLocal {
+		// the whole sequence is aligned, bases before the first node are insertions
+		for j := 1; j <= lX; j++ {
+			scores.SetValue(j, 0, aln.openGapScore+float64(j-1)*aln.extendGapScore)
+			backGrphMatrix.SetValue(j, 0, 0)
+			backSeqMatrix.SetValue(j, 0, float64(j-1))
+			insertCostMatrix.SetValue(j, 0, aln.extendGapScore)
+		}
+	}
+
 	for i, nodeIdx := range g.nodeList {
-		node := g.nodeDict[nodeIdx]
+		node := g.nodes[nodeIdx]
 		pbase := node.base
+		previousIdxs := node.predRanks
+
+		if aln.mode == AlignGlobal {
+			// nodes before the first base of the sequence are deletions
+			best, bestPred := math.Inf(-1), -1
+			for _, predIdx := range previousIdxs {
+				if score := scores.GetValue(0, predIdx+1) + deleteCostMatrix.GetValue(0, predIdx+1); score > best {
+					best, bestPred = score, predIdx
+				}
+			}
+			scores.SetValue(0, i+1, best)
+			backGrphMatrix.SetValue(0, i+1, float64(bestPred+1))
+			backSeqMatrix.SetValue(0, i+1, 0)
+			deleteCostMatrix.SetValue(0, i+1, aln.extendGapScore)
+		}
 
 		for j, sbase := range sequence {
-			candidates := make([]*MoveOption, 0) // could be optimized if I know the pred nodes already
+			candidates := make([]*MoveOption, 0)
 			insScore := scores.GetValue(j, i+1) + insertCostMatrix.GetValue(j, i+1)
 			insertOption := MoveOptionConstruct(insScore, i+1, j, "INSERT")
 			candidates = append(candidates, insertOption)
-			previousIdxs := prevIndices(node, IdToIndex)
 			for _, predIdx := range previousIdxs {
 				// handle the matches
 				matchScore := scores.GetValue(j, predIdx+1) + aln.MatchBases(pbase, string(sbase))
@@ -270,7 +366,7 @@ func AlignStringToGraph(g *PoaGraph, aln *PairwiseAlignmentParameters, sequence,
 			if maxMove.moveType == "DELETE" {
 				deleteCostMatrix.SetValue(j+1, i+1, aln.extendGapScore)
 			}
-			if scores.GetValue(j+1, i+1) < 0 {
+			if aln.mode == AlignLocal && scores.GetValue(j+1, i+1) < 0 {
 				scores.SetValue(j+1, i+1, 0)
 				backGrphMatrix.SetValue(j+1, i+1, -1)
 				backSeqMatrix.SetValue(j+1, i+1, -1)
@@ -278,9 +374,11 @@ func AlignStringToGraph(g *PoaGraph, aln *PairwiseAlignmentParameters, sequence,
 		}
 	}
 
-	strIdxs, matches := DoTraceBack(scores, backSeqMatrix, backGrphMatrix, IndexToId)
+	besti, bestj, score := traceBackStart(g, scores, aln.mode)
+	strIdxs, matches := traceBackFrom(besti, bestj, scores, backSeqMatrix, backGrphMatrix, g.nodeList, aln.mode)
 
 	pA := PairwiseAlignmentConstruct(strIdxs, matches, sequence, label)
+	pA.score = score
 
 	return pA
 	//return strIdxs, matches
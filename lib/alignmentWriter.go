@@ -0,0 +1,466 @@
+package PoaGo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AlignedRow : one row of the alignment, Seq has a "-" in the columns the sequence doesn't cover
+type AlignedRow struct {
+	Name    string
+	Seq     string
+	Reverse bool       // the sequence was added as its reverse complement
+	Record  *SeqRecord // metadata of the sequence, nil for the consensus rows
+}
+
+// DisplayName is the name with a suffix for reverse complemented sequences
+func (self AlignedRow) DisplayName() string {
+	if self.Reverse {
+		return self.Name + reverseSuffix
+	}
+	return self.Name
+}
+
+// AlignmentHeader : what a writer knows before the first row
+type AlignmentHeader struct {
+	NbColumns  int
+	NbRows     int          // number of sequence rows
+	NameWidth  int          // longest display name, including the consensus names
+	Consensus  []AlignedRow // all consensus rows, also when they aren't written as rows
+	ReadGroups []ReadGroup  // read groups of the sequences
+}
+
+// AlignmentWriter : receives the alignment row by row. Begin is called first, then WriteRow once per
+// sequence, then WriteConsensus for each consensus row if they are requested, and End last
+type AlignmentWriter interface {
+	Begin(header AlignmentHeader) error
+	WriteRow(row AlignedRow) error
+	WriteConsensus(row AlignedRow) error
+	End() error
+}
+
+type AlignmentOptions struct {
+	Consensus       bool    // write the consensus rows after the sequences
+	MaxFraction     float64 // see AllConsensuses
+	ConsensusPrefix string  // consensus rows are named prefix0, prefix1, ...
+}
+
+func AlignmentOptionsDefault() AlignmentOptions {
+	return AlignmentOptions{Consensus: true, MaxFraction: DefaultMaxFraction, ConsensusPrefix: "Consensus"}
+}
+
+// WriteAlignment sends the alignment to w one row at a time, the rows are built as they are written
+// so only one of them is in memory at a time
+func (self *PoaGraph) WriteAlignment(w AlignmentWriter, opts AlignmentOptions) error {
+	// the consensus sorts the graph if needed, do it before assigning the columns
+	consensusPaths, consensusBases, nbConsensus := self.AllConsensuses(opts.MaxFraction)
+	columnIndex, nColumns := self.columnIndex()
+
+	header := AlignmentHeader{NbColumns: nColumns, NbRows: len(self.records), Consensus: make([]AlignedRow, 0, nbConsensus),
+		ReadGroups: self.ReadGroups()}
+	for i := 0; i < nbConsensus; i++ {
+		path := *consensusPaths[i]
+		bases := *consensusBases[i]
+
+		if len(path) != len(bases) {
+			e := fmt.Sprintf("Consensus %v doesn't have correct length", i)
+			panic(e)
+		}
+
+		charList := makeAlignmentColumnArray(nColumns)
+		for col := 0; col < len(path); col++ {
+			charList[columnIndex[path[col]]] = bases[col]
+		}
+		row := AlignedRow{Name: fmt.Sprintf("%s%v", opts.ConsensusPrefix, i), Seq: strings.Join(charList, "")}
+		header.Consensus = append(header.Consensus, row)
+		header.NameWidth = max(header.NameWidth, len(row.Name))
+	}
+	for i := range self.records {
+		header.NameWidth = max(header.NameWidth, len(self.displayName(i)))
+	}
+
+	if err := w.Begin(header); err != nil {
+		return err
+	}
+	for i, rec := range self.records {
+		row := AlignedRow{Name: rec.Name, Seq: strings.Join(self.alignmentRow(i, columnIndex, nColumns), ""),
+			Reverse: rec.Reverse, Record: rec}
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	if opts.Consensus {
+		for _, row := range header.Consensus {
+			if err := w.WriteConsensus(row); err != nil {
+				return err
+			}
+		}
+	}
+	return w.End()
+}
+
+// collects the rows as strings, used by GenerateAlignmentStrings
+type stringsAlignmentWriter struct {
+	names, rows []string
+}
+
+func (self *stringsAlignmentWriter) Begin(header AlignmentHeader) error {
+	self.names = make([]string, 0, header.NbRows+len(header.Consensus))
+	self.rows = make([]string, 0, header.NbRows+len(header.Consensus))
+	return nil
+}
+
+func (self *stringsAlignmentWriter) WriteRow(row AlignedRow) error {
+	self.names = append(self.names, row.DisplayName())
+	self.rows = append(self.rows, row.Seq)
+	return nil
+}
+
+func (self *stringsAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	return self.WriteRow(row)
+}
+
+func (self *stringsAlignmentWriter) End() error {
+	return nil
+}
+
+// collects the rows with the header, used by the html report and the variant caller
+type collectAlignmentWriter struct {
+	header AlignmentHeader
+	rows   []AlignedRow
+}
+
+func (self *collectAlignmentWriter) Begin(header AlignmentHeader) error {
+	self.header = header
+	self.rows = make([]AlignedRow, 0, header.NbRows+len(header.Consensus))
+	return nil
+}
+
+func (self *collectAlignmentWriter) WriteRow(row AlignedRow) error {
+	self.rows = append(self.rows, row)
+	return nil
+}
+
+func (self *collectAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	return self.WriteRow(row)
+}
+
+func (self *collectAlignmentWriter) End() error {
+	return nil
+}
+
+// PlainAlignmentWriter : one line per row, name and aligned sequence separated by a tab
+type PlainAlignmentWriter struct {
+	w io.Writer
+}
+
+func PlainAlignmentWriterConstruct(w io.Writer) *PlainAlignmentWriter {
+	return &PlainAlignmentWriter{w: w}
+}
+
+func (self *PlainAlignmentWriter) Begin(header AlignmentHeader) error {
+	return nil
+}
+
+func (self *PlainAlignmentWriter) WriteRow(row AlignedRow) error {
+	_, err := fmt.Fprintf(self.w, "%-12s\t%-6s\n", row.DisplayName(), row.Seq)
+	return err
+}
+
+func (self *PlainAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	return self.WriteRow(row)
+}
+
+func (self *PlainAlignmentWriter) End() error {
+	return nil
+}
+
+// FastaAlignmentWriter : aligned fasta, the consensus rows come after the sequences
+type FastaAlignmentWriter struct {
+	w io.Writer
+}
+
+func FastaAlignmentWriterConstruct(w io.Writer) *FastaAlignmentWriter {
+	return &FastaAlignmentWriter{w: w}
+}
+
+func (self *FastaAlignmentWriter) Begin(header AlignmentHeader) error {
+	return nil
+}
+
+func (self *FastaAlignmentWriter) WriteRow(row AlignedRow) error {
+	return WriteFasta(self.w, row.DisplayName(), row.Seq)
+}
+
+func (self *FastaAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	return WriteFasta(self.w, row.Name, row.Seq)
+}
+
+func (self *FastaAlignmentWriter) End() error {
+	return nil
+}
+
+// StockholmAlignmentWriter : Stockholm 1.0, the consensus rows are written as #=GC seq_cons lines
+type StockholmAlignmentWriter struct {
+	w         io.Writer
+	nameWidth int
+	nbCons    int
+}
+
+func StockholmAlignmentWriterConstruct(w io.Writer) *StockholmAlignmentWriter {
+	return &StockholmAlignmentWriter{w: w}
+}
+
+func (self *StockholmAlignmentWriter) Begin(header AlignmentHeader) error {
+	// leave room for the "#=GC seq_consN" names
+	self.nameWidth = max(header.NameWidth, len("#=GC seq_cons"))
+	if len(header.Consensus) > 1 {
+		self.nameWidth = max(self.nameWidth, len("#=GC seq_cons")+len(fmt.Sprint(len(header.Consensus)-1)))
+	}
+	self.nbCons = 0
+	_, err := fmt.Fprint(self.w, "# STOCKHOLM 1.0\n\n")
+	return err
+}
+
+func (self *StockholmAlignmentWriter) WriteRow(row AlignedRow) error {
+	_, err := fmt.Fprintf(self.w, "%-*s %s\n", self.nameWidth, row.DisplayName(), row.Seq)
+	return err
+}
+
+func (self *StockholmAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	name := "#=GC seq_cons"
+	if self.nbCons > 0 {
+		name += fmt.Sprint(self.nbCons)
+	}
+	self.nbCons += 1
+	_, err := fmt.Fprintf(self.w, "%-*s %s\n", self.nameWidth, name, row.Seq)
+	return err
+}
+
+func (self *StockholmAlignmentWriter) End() error {
+	_, err := fmt.Fprint(self.w, "//\n")
+	return err
+}
+
+// ClustalAlignmentWriter : CLUSTAL format in blocks of 60 columns, the consensus rows come after the
+// sequences in each block, followed by the conservation line. The rows are kept until End since
+// every block has a piece of every row
+type ClustalAlignmentWriter struct {
+	w         io.Writer
+	nameWidth int
+	rows      []AlignedRow
+	consensus []AlignedRow
+}
+
+const clustalBlockWidth int = 60
+
+func ClustalAlignmentWriterConstruct(w io.Writer) *ClustalAlignmentWriter {
+	return &ClustalAlignmentWriter{w: w}
+}
+
+func (self *ClustalAlignmentWriter) Begin(header AlignmentHeader) error {
+	self.nameWidth = header.NameWidth
+	self.rows = make([]AlignedRow, 0, header.NbRows)
+	self.consensus = make([]AlignedRow, 0)
+	return nil
+}
+
+func (self *ClustalAlignmentWriter) WriteRow(row AlignedRow) error {
+	self.rows = append(self.rows, row)
+	return nil
+}
+
+func (self *ClustalAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	self.consensus = append(self.consensus, row)
+	return nil
+}
+
+// '*' for the columns where all sequences have the same residue
+func clustalConservation(rows []AlignedRow, start, end int) string {
+	line := make([]byte, end-start)
+	for col := start; col < end; col++ {
+		line[col-start] = '*'
+		for _, row := range rows {
+			if row.Seq[col] == '-' || row.Seq[col] != rows[0].Seq[col] {
+				line[col-start] = ' '
+				break
+			}
+		}
+	}
+	return string(line)
+}
+
+func (self *ClustalAlignmentWriter) End() error {
+	var b strings.Builder
+	// the blocks start with the blank line separating them from the header or the block before
+	b.WriteString("CLUSTAL W multiple sequence alignment (PoaGo)\n")
+	nColumns := 0
+	if len(self.rows) > 0 {
+		nColumns = len(self.rows[0].Seq)
+	}
+	for start := 0; start < nColumns; start += clustalBlockWidth {
+		end := min(start+clustalBlockWidth, nColumns)
+		b.WriteString("\n")
+		for _, row := range self.rows {
+			fmt.Fprintf(&b, "%-*s      %s\n", self.nameWidth, row.DisplayName(), row.Seq[start:end])
+		}
+		for _, row := range self.consensus {
+			fmt.Fprintf(&b, "%-*s      %s\n", self.nameWidth, row.Name, row.Seq[start:end])
+		}
+		fmt.Fprintf(&b, "%-*s      %s\n", self.nameWidth, "", clustalConservation(self.rows, start, end))
+	}
+	_, err := io.WriteString(self.w, b.String())
+	return err
+}
+
+// SamAlignmentWriter : every sequence as a SAM record aligned to the first consensus, which is the
+// only reference sequence. Columns where both have a residue are M, residues in columns without
+// consensus are insertions (soft clips at the ends) and consensus residues without a sequence
+// residue are deletions
+type SamAlignmentWriter struct {
+	w         io.Writer
+	reference AlignedRow
+}
+
+func SamAlignmentWriterConstruct(w io.Writer) *SamAlignmentWriter {
+	return &SamAlignmentWriter{w: w}
+}
+
+func (self *SamAlignmentWriter) Begin(header AlignmentHeader) error {
+	if len(header.Consensus) == 0 {
+		return errors.New("SAM output needs a consensus as reference")
+	}
+	self.reference = header.Consensus[0]
+	refLength := len(strings.Replace(self.reference.Seq, "-", "", -1))
+	var b strings.Builder
+	fmt.Fprintf(&b, "@HD\tVN:1.6\tSO:unsorted\n@SQ\tSN:%s\tLN:%d\n", self.reference.Name, refLength)
+	for _, rg := range header.ReadGroups {
+		fmt.Fprintf(&b, "@RG\tID:%s", rg.Id)
+		if rg.Sample != "" {
+			fmt.Fprintf(&b, "\tSM:%s", rg.Sample)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(Program.samHeader())
+	_, err := io.WriteString(self.w, b.String())
+	return err
+}
+
+// SamRecordFields returns the position (1-based, 0 if unaligned), the CIGAR string and the ungapped
+// sequence of a row aligned to the gapped reference
+func SamRecordFields(reference, row string) (int, string, string) {
+	ops := make([]CigarOp, 0)
+	push := func(kind byte) {
+		if len(ops) > 0 && ops[len(ops)-1].Op == kind {
+			ops[len(ops)-1].Len += 1
+		} else {
+			ops = append(ops, CigarOp{Op: kind, Len: 1})
+		}
+	}
+
+	seq := make([]byte, 0, len(row))
+	pos, refPos := 0, 0
+	for col := 0; col < len(row); col++ {
+		r, c := row[col], reference[col]
+		switch {
+		case r != '-' && c != '-':
+			refPos += 1
+			if pos == 0 {
+				pos = refPos
+			}
+			push('M')
+		case r != '-':
+			push('I')
+		case c != '-':
+			refPos += 1
+			if pos > 0 {
+				push('D')
+			}
+		}
+		if r != '-' {
+			seq = append(seq, r)
+		}
+	}
+	if pos == 0 {
+		return 0, "*", string(seq)
+	}
+
+	// insertions at the ends are soft clips, deletions after the last match are dropped
+	lastMatch := 0
+	for i, o := range ops {
+		if o.Op == 'M' {
+			lastMatch = i
+		}
+	}
+	clipped := 0
+	for _, o := range ops[lastMatch+1:] {
+		if o.Op == 'I' {
+			clipped += o.Len
+		}
+	}
+	ops = ops[:lastMatch+1]
+	if clipped > 0 {
+		ops = append(ops, CigarOp{Op: 'S', Len: clipped})
+	}
+	if ops[0].Op == 'I' {
+		ops[0].Op = 'S'
+	}
+	return pos, CigarString(ops), string(seq)
+}
+
+func (self *SamAlignmentWriter) WriteRow(row AlignedRow) error {
+	pos, cigar, seq := SamRecordFields(self.reference.Seq, row.Seq)
+	flag, rname := 0, self.reference.Name
+	if row.Reverse {
+		flag |= 0x10
+	}
+	if pos == 0 {
+		flag, rname = 0x4, "*"
+	}
+	if seq == "" {
+		// a row of gaps only
+		seq = "*"
+	}
+	qual, tags := "*", ""
+	if row.Record != nil {
+		if len(row.Record.Qual) == len(seq) && seq != "*" {
+			qual = row.Record.Qual
+		}
+		if row.Record.ReadGroup != "" {
+			tags = "\tRG:Z:" + row.Record.ReadGroup
+		}
+	}
+	_, err := fmt.Fprintf(self.w, "%s\t%d\t%s\t%d\t255\t%s\t*\t0\t0\t%s\t%s%s\n", row.Name, flag, rname, pos, cigar, seq,
+		qual, tags)
+	return err
+}
+
+// the consensus is the reference, it isn't written as a record
+func (self *SamAlignmentWriter) WriteConsensus(row AlignedRow) error {
+	return nil
+}
+
+func (self *SamAlignmentWriter) End() error {
+	return nil
+}
+
+// AlignmentWriterForFormat returns the writer for one of the supported output formats: plain,
+// fasta, clustal, stockholm or sam
+func AlignmentWriterForFormat(format string, w io.Writer) (AlignmentWriter, error) {
+	switch strings.ToLower(format) {
+	case "plain":
+		return PlainAlignmentWriterConstruct(w), nil
+	case "fasta":
+		return FastaAlignmentWriterConstruct(w), nil
+	case "clustal":
+		return ClustalAlignmentWriterConstruct(w), nil
+	case "stockholm":
+		return StockholmAlignmentWriterConstruct(w), nil
+	case "sam":
+		return SamAlignmentWriterConstruct(w), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown output format %v, should be plain, fasta, clustal, stockholm or sam", format))
+	}
+}
@@ -0,0 +1,113 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func writerTestGraph() *PoaGraph {
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGT", "base", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACT", "new"))
+	return g
+}
+
+// records the calls the writer gets
+type recordingWriter struct {
+	calls []string
+}
+
+func (self *recordingWriter) Begin(header AlignmentHeader) error {
+	self.calls = append(self.calls, "begin")
+	return nil
+}
+
+func (self *recordingWriter) WriteRow(row AlignedRow) error {
+	self.calls = append(self.calls, "row "+row.Name+" "+row.Seq)
+	return nil
+}
+
+func (self *recordingWriter) WriteConsensus(row AlignedRow) error {
+	self.calls = append(self.calls, "consensus "+row.Name+" "+row.Seq)
+	return nil
+}
+
+func (self *recordingWriter) End() error {
+	self.calls = append(self.calls, "end")
+	return nil
+}
+
+func TestPoaGraph_WriteAlignment(t *testing.T) {
+	g := writerTestGraph()
+	w := &recordingWriter{}
+	assert.Nil(t, g.WriteAlignment(w, AlignmentOptionsDefault()))
+	assert.Equal(t, []string{"begin", "row base ACGT", "row new AC-T", "consensus Consensus0 ACGT", "end"}, w.calls)
+
+	opts := AlignmentOptionsDefault()
+	opts.Consensus = false
+	w = &recordingWriter{}
+	assert.Nil(t, g.WriteAlignment(w, opts))
+	assert.Equal(t, []string{"begin", "row base ACGT", "row new AC-T", "end"}, w.calls)
+}
+
+func TestAlignmentWriterForFormat(t *testing.T) {
+	g := writerTestGraph()
+	expected := map[string]string{
+		"plain":     "base        \tACGT  \nnew         \tAC-T  \nConsensus0  \tACGT  \n",
+		"fasta":     ">base\nACGT\n>new\nAC-T\n>Consensus0\nACGT\n",
+		"stockholm": "# STOCKHOLM 1.0\n\nbase          ACGT\nnew           AC-T\n#=GC seq_cons ACGT\n//\n",
+		"sam": "@HD\tVN:1.6\tSO:unsorted\n@SQ\tSN:Consensus0\tLN:4\n@PG\tID:PoaGo\tPN:PoaGo\tVN:NOTSET\n" +
+			"base\t0\tConsensus0\t1\t255\t4M\t*\t0\t0\tACGT\t*\nnew\t0\tConsensus0\t1\t255\t2M1D1M\t*\t0\t0\tACT\t*\n",
+	}
+	for format, output := range expected {
+		var buf bytes.Buffer
+		w, err := AlignmentWriterForFormat(format, &buf)
+		assert.Nil(t, err)
+		assert.Nil(t, g.WriteAlignment(w, AlignmentOptionsDefault()))
+		assert.Equal(t, output, buf.String(), format)
+	}
+
+	var buf bytes.Buffer
+	w, _ := AlignmentWriterForFormat("clustal", &buf)
+	assert.Nil(t, g.WriteAlignment(w, AlignmentOptionsDefault()))
+	msa, err := ReadClustal(strings.NewReader(buf.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"base", "new", "Consensus0"}, msa.Names)
+	assert.Contains(t, buf.String(), "\n                ** *\n")
+	// one blank line between the header and the first block
+	assert.True(t, strings.HasPrefix(buf.String(), "CLUSTAL W multiple sequence alignment (PoaGo)\n\nbase            ACGT\n"), buf.String())
+
+	_, err = AlignmentWriterForFormat("nexus", &buf)
+	assert.NotNil(t, err)
+}
+
+func TestSamAlignmentWriter_WriteRow(t *testing.T) {
+	var buf bytes.Buffer
+	w := SamAlignmentWriterConstruct(&buf)
+	assert.Nil(t, w.Begin(AlignmentHeader{Consensus: []AlignedRow{{Name: "Consensus0", Seq: "ACGT"}}}))
+	buf.Reset()
+
+	// a row without bases is unmapped and has no sequence
+	assert.Nil(t, w.WriteRow(AlignedRow{Name: "gaps", Seq: "----", Record: &SeqRecord{Name: "gaps"}}))
+	assert.Nil(t, w.WriteRow(AlignedRow{Name: "read", Seq: "-CG-", Record: &SeqRecord{Name: "read", Qual: "AB"}}))
+	assert.Equal(t, "gaps\t4\t*\t0\t255\t*\t*\t0\t0\t*\t*\n"+
+		"read\t0\tConsensus0\t2\t255\t2M\t*\t0\t0\tCG\tAB\n", buf.String())
+}
+
+func TestSamRecordFields(t *testing.T) {
+	pos, cigar, seq := SamRecordFields("--ACGT-AC--", "TTAC-TGACGG")
+	assert.Equal(t, 1, pos)
+	assert.Equal(t, "2S2M1D1M1I2M2S", cigar)
+	assert.Equal(t, "TTACTGACGG", seq)
+
+	pos, cigar, _ = SamRecordFields("ACGT", "--GT")
+	assert.Equal(t, 3, pos)
+	assert.Equal(t, "2M", cigar)
+
+	pos, cigar, _ = SamRecordFields("AC--", "--GT")
+	assert.Equal(t, 0, pos)
+	assert.Equal(t, "*", cigar)
+}
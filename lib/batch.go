@@ -0,0 +1,60 @@
+package PoaGo
+
+import (
+	"sync"
+)
+
+// AlignOrientedBatch aligns the sequences to the graph with up to threads goroutines. They are all
+// aligned to the graph as it is now, so the alignments don't depend on threads. They are returned in
+// the order of the sequences and can be added with AddSequenceAlignment afterwards. With more than one
+// sequence per batch the result can differ from aligning and adding them one at a time, a sequence
+// doesn't see the ones before it in the same batch
+func AlignOrientedBatch(g *PoaGraph, aln *PairwiseAlignmentParameters, sequences, labels []string, mode OrientationMode, threads int) []*PairwiseAlignment {
+	if len(sequences) != len(labels) {
+		panic("number of sequences != number of labels")
+	}
+	alignments := make([]*PairwiseAlignment, len(sequences))
+
+	// everything that changes the graph during an alignment is done before starting
+	g.ensureSorted()
+	if mode == OrientKmer {
+		g.updateKmerIndex(orientationKmerSize)
+	}
+
+	parallelFor(len(sequences), threads, func(i int) error {
+		alignments[i] = AlignOrientedStringToGraph(g, aln, sequences[i], labels[i], mode)
+		return nil
+	})
+	return alignments
+}
+
+// parallelFor calls f for i from 0 to n-1 with up to threads goroutines, each taking the next i when
+// it is done with the last one. All the calls are made, the error returned is the one of the lowest i
+func parallelFor(n, threads int, f func(i int) error) error {
+	if threads < 1 {
+		threads = 1
+	}
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package PoaGo
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAlignOrientedBatch(t *testing.T) {
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("GATTACAGGCATTCCA", "base", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	seqs := []string{"GATTACAGG", "GATTCCAGGCATT", ReverseComplement("GATTACAGGCATTCCA"), "CAGGCATTCCA"}
+	labels := []string{"r1", "r2", "r3", "r4"}
+
+	alignments := AlignOrientedBatch(g, aln, seqs, labels, OrientKmer, 3)
+	for i, pA := range alignments {
+		expected := AlignOrientedStringToGraph(g, aln, seqs[i], labels[i], OrientKmer)
+		assert.Equal(t, expected.stringIdxs, pA.stringIdxs, labels[i])
+		assert.Equal(t, expected.matches, pA.matches, labels[i])
+		assert.Equal(t, expected.reverse, pA.reverse, labels[i])
+	}
+	assert.True(t, alignments[2].Reverse())
+
+	for _, pA := range alignments {
+		g.AddSequenceAlignment(pA)
+	}
+	names, _ := g.GenerateAlignmentStrings()
+	assert.Equal(t, []string{"base", "r1", "r2", "r3_rc", "r4", "Consensus0"}, names)
+}
+
+func TestParallelFor(t *testing.T) {
+	for _, threads := range []int{-1, 0, 1, 3, 20} {
+		calls := make([]int, 10)
+		err := parallelFor(len(calls), threads, func(i int) error {
+			calls[i] += 1
+			if i == 4 || i == 7 {
+				return fmt.Errorf("call %d", i)
+			}
+			return nil
+		})
+		// every call is made and the error of the first failing one is returned
+		assert.Equal(t, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, calls)
+		assert.EqualError(t, err, "call 4")
+	}
+	assert.Nil(t, parallelFor(0, 2, func(i int) error { return fmt.Errorf("call %d", i) }))
+}
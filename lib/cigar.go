@@ -0,0 +1,171 @@
+package PoaGo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CigarOp : an operation of a CIGAR string, M I D N S H P = or X, repeated Len times
+type CigarOp struct {
+	Op  byte
+	Len int
+}
+
+// consumesQuery is true for the operations that go over bases of the read
+func (self CigarOp) consumesQuery() bool {
+	return strings.IndexByte("MIS=X", self.Op) >= 0
+}
+
+// consumesTarget is true for the operations that go over bases of the target
+func (self CigarOp) consumesTarget() bool {
+	return strings.IndexByte("MDN=X", self.Op) >= 0
+}
+
+// ParseCigar parses a CIGAR string, * is an empty CIGAR. Clips are only allowed at the ends
+func ParseCigar(cigar string) ([]CigarOp, error) {
+	ops := make([]CigarOp, 0)
+	if cigar == "*" {
+		return ops, nil
+	}
+	n := 0
+	for i := 0; i < len(cigar); i++ {
+		c := cigar[i]
+		if c >= '0' && c <= '9' {
+			n = n*10 + int(c-'0')
+			continue
+		}
+		if strings.IndexByte("MIDNSHP=X", c) < 0 {
+			return nil, fmt.Errorf("ParseCigar: unknown operation %c in %v", c, cigar)
+		}
+		if i == 0 || cigar[i-1] < '0' || cigar[i-1] > '9' {
+			return nil, fmt.Errorf("ParseCigar: operation %c without a length in %v", c, cigar)
+		}
+		ops = append(ops, CigarOp{Op: c, Len: n})
+		n = 0
+	}
+	if len(cigar) > 0 && cigar[len(cigar)-1] >= '0' && cigar[len(cigar)-1] <= '9' {
+		return nil, fmt.Errorf("ParseCigar: %v ends with a length", cigar)
+	}
+	if err := checkClips(ops); err != nil {
+		return nil, fmt.Errorf("ParseCigar: %v in %v", err, cigar)
+	}
+	return ops, nil
+}
+
+// checkClips returns an error if the hard clips aren't the first or last operations, or the soft
+// clips aren't at the ends inside them
+func checkClips(ops []CigarOp) error {
+	first, last := 0, len(ops)-1
+	if len(ops) > 0 && ops[first].Op == 'H' {
+		first += 1
+	}
+	if last >= first && ops[last].Op == 'H' {
+		last -= 1
+	}
+	for i, op := range ops {
+		switch {
+		case op.Op == 'H' && (i >= first && i <= last):
+			return fmt.Errorf("hard clip %d%c between other operations", op.Len, op.Op)
+		case op.Op == 'S' && i != first && i != last:
+			return fmt.Errorf("soft clip %d%c between other operations", op.Len, op.Op)
+		}
+	}
+	return nil
+}
+
+// CigarString writes the operations as a CIGAR string, * if there are none
+func CigarString(ops []CigarOp) string {
+	if len(ops) == 0 {
+		return "*"
+	}
+	var b strings.Builder
+	for _, op := range ops {
+		b.WriteString(strconv.Itoa(op.Len))
+		b.WriteByte(op.Op)
+	}
+	return b.String()
+}
+
+// number of read and target bases the operations go over
+func cigarLengths(ops []CigarOp) (int, int) {
+	queryLen, targetLen := 0, 0
+	for _, op := range ops {
+		if op.consumesQuery() {
+			queryLen += op.Len
+		}
+		if op.consumesTarget() {
+			targetLen += op.Len
+		}
+	}
+	return queryLen, targetLen
+}
+
+// PairwiseAlignmentFromCigar makes the alignment of a sequence to a path of the graph, e.g. the path
+// of a backbone sequence from PoaGraph.Path, from a CIGAR computed by a mapper. The CIGAR starts at
+// position start of the path and goes over the whole sequence, soft clips included; soft clipped bases
+// are added by AddSequenceAlignment as ragged ends. Mismatches are found from the bases of the nodes
+func PairwiseAlignmentFromCigar(path []int, start int, sequence, label string, cigar []CigarOp) (*PairwiseAlignment, error) {
+	if err := checkClips(cigar); err != nil {
+		return nil, fmt.Errorf("PairwiseAlignmentFromCigar: CIGAR of %v has a %v", label, err)
+	}
+	queryLen, targetLen := cigarLengths(cigar)
+	if queryLen != len(sequence) {
+		return nil, fmt.Errorf("PairwiseAlignmentFromCigar: CIGAR of %v goes over %v bases, the sequence has %v",
+			label, queryLen, len(sequence))
+	}
+	if start < 0 || start+targetLen > len(path) {
+		return nil, fmt.Errorf("PairwiseAlignmentFromCigar: CIGAR of %v goes from %v over %v nodes, the path has %v",
+			label, start, targetLen, len(path))
+	}
+	strIdxs, matches := make([]int, 0, len(sequence)), make([]int, 0, len(sequence))
+	qi, ti := 0, start
+	for _, op := range cigar {
+		for k := 0; k < op.Len; k++ {
+			switch {
+			case op.Op == 'S':
+			case op.consumesQuery() && op.consumesTarget():
+				strIdxs, matches = append(strIdxs, qi), append(matches, path[ti])
+			case op.consumesQuery():
+				strIdxs, matches = append(strIdxs, qi), append(matches, -1)
+			case op.consumesTarget():
+				strIdxs, matches = append(strIdxs, -1), append(matches, path[ti])
+			}
+			if op.consumesQuery() {
+				qi += 1
+			}
+			if op.consumesTarget() {
+				ti += 1
+			}
+		}
+	}
+	return PairwiseAlignmentConstruct(strIdxs, matches, sequence, label), nil
+}
+
+// queryOffsets returns, for each target position of the mapping in increasing order, the offset in
+// the mapped part of the read, in the orientation of the target, of the first base aligned at or
+// after it. Insertions go with the target position before them
+func (self ReadMapping) queryOffsets(targets []int) []int {
+	offsets := make([]int, len(targets))
+	next := 0
+	qi, ti := 0, self.TargetStart
+	for _, op := range self.Cigar {
+		if op.consumesTarget() {
+			for next < len(targets) && targets[next] < ti+op.Len {
+				offsets[next] = qi
+				if op.consumesQuery() {
+					offsets[next] += intArrayMax([]int{targets[next] - ti, 0})
+				}
+				next += 1
+			}
+			ti += op.Len
+		}
+		if op.consumesQuery() {
+			qi += op.Len
+		}
+	}
+	for ; next < len(targets); next++ {
+		offsets[next] = qi
+	}
+	return offsets
+}
@@ -0,0 +1,71 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCigar(t *testing.T) {
+	ops, err := ParseCigar("3S10M2I1D4=1X12N5H")
+	assert.Nil(t, err)
+	assert.Equal(t, []CigarOp{{'S', 3}, {'M', 10}, {'I', 2}, {'D', 1}, {'=', 4}, {'X', 1}, {'N', 12}, {'H', 5}}, ops)
+	assert.Equal(t, "3S10M2I1D4=1X12N5H", CigarString(ops))
+	queryLen, targetLen := cigarLengths(ops)
+	assert.Equal(t, 20, queryLen)
+	assert.Equal(t, 28, targetLen)
+
+	ops, err = ParseCigar("*")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ops))
+	assert.Equal(t, "*", CigarString(ops))
+
+	// clips only at the ends, the soft clips inside the hard clips
+	ops, err = ParseCigar("2H3S4M1S5H")
+	assert.Nil(t, err)
+	assert.Equal(t, []CigarOp{{'H', 2}, {'S', 3}, {'M', 4}, {'S', 1}, {'H', 5}}, ops)
+
+	for _, cigar := range []string{"10M5", "M", "10M2Q", "3MM", "3M2S3M", "3M2H3M", "3S2H3M", "3M1H2S"} {
+		_, err = ParseCigar(cigar)
+		assert.NotNil(t, err, cigar)
+	}
+}
+
+func TestPairwiseAlignmentFromCigar(t *testing.T) {
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("ACGTACGT", "backbone", true)
+	path, ok := g.Path("backbone")
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, path)
+	_, ok = g.Path("other")
+	assert.False(t, ok)
+
+	// GG clipped, CGT matched from the second node, AA inserted, A deleted, GA against CG
+	cigar, _ := ParseCigar("2S3M2I1D2M")
+	pA, err := PairwiseAlignmentFromCigar(path, 1, "GGCGTAAGA", "read", cigar)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 3, 4, 5, 6, -1, 7, 8}, pA.stringIdxs)
+	assert.Equal(t, []int{1, 2, 3, -1, -1, 4, 5, 6}, pA.matches)
+
+	assert.Nil(t, g.AddSequenceAlignment(pA))
+	seq, _ := g.Sequence("read")
+	assert.Equal(t, "GGCGTAAGA", seq)
+	assert.Nil(t, g.Validate())
+	// the matched bases share the nodes of the backbone
+	assert.Equal(t, []string{"backbone", "read"}, g.NodeLabels(2))
+	assert.Equal(t, []string{"backbone"}, g.NodeLabels(5))
+
+	_, err = PairwiseAlignmentFromCigar(path, 1, "GGCGTAAG", "read", cigar)
+	assert.NotNil(t, err)
+	_, err = PairwiseAlignmentFromCigar(path, 3, "GGCGTAAGA", "read", cigar)
+	assert.NotNil(t, err)
+	// a soft clip in the middle isn't a clip
+	_, err = PairwiseAlignmentFromCigar(path, 0, "ACGTAGGT", "read", []CigarOp{{'M', 3}, {'S', 2}, {'M', 3}})
+	assert.NotNil(t, err)
+}
+
+func TestReadMapping_queryOffsets(t *testing.T) {
+	cigar, _ := ParseCigar("3M2I2D3M")
+	m := ReadMapping{TargetStart: 10, TargetEnd: 18, Cigar: cigar}
+	// the insertion goes with target position 12, the deletion has no read base
+	assert.Equal(t, []int{0, 2, 5, 5, 5, 6, 8, 8}, m.queryOffsets([]int{10, 12, 13, 14, 15, 16, 18, 20}))
+}
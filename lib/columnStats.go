@@ -0,0 +1,114 @@
+package PoaGo
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// ColumnStats : summary of one column of the multiple sequence alignment
+type ColumnStats struct {
+	Column             int
+	Depth              int     // number of sequences with a residue in this column
+	Gaps               int     // number of sequences with a gap in this column
+	GapFraction        float64 // Gaps / number of sequences
+	Entropy            float64 // Shannon entropy (bits) of the residues, gaps not counted
+	MajorityBase       string  // most frequent residue, "-" for an empty column
+	MajorityFrequency  float64 // frequency of the majority residue among the residues
+	ConsensusBase      string  // base of the consensus in this column, "-" if the consensus has a gap
+	ConsensusAgreement float64 // fraction of sequences matching the consensus (gap or residue)
+}
+
+// ColumnStatistics computes per column metrics of the alignment that GenerateAlignmentStrings
+// produces, working directly on the nodes along each sequence's path
+func (self *PoaGraph) ColumnStatistics() []ColumnStats {
+	if len(self.nodeList) == 0 {
+		return []ColumnStats{}
+	}
+	// make sure the graph is sorted before assigning columns
+	path, bases, _ := self.consensus(nil)
+	columnIndex, nColumns := self.columnIndex()
+
+	// counts of each residue in each column
+	counts := make([]map[string]int, nColumns)
+	for col := 0; col < nColumns; col++ {
+		counts[col] = make(map[string]int)
+	}
+	for _, rec := range self.records {
+		for nodeId := rec.start; nodeId >= 0; {
+			node := self.nodes[nodeId]
+			counts[columnIndex[nodeId]][node.base] += 1
+			nodeId = node.NextNode(rec.Id)
+		}
+	}
+
+	consensusColumns := makeAlignmentColumnArray(nColumns)
+	for i, nodeId := range path {
+		consensusColumns[columnIndex[nodeId]] = bases[i]
+	}
+
+	nbSeqs := len(self.records)
+	stats := make([]ColumnStats, nColumns)
+	for col := 0; col < nColumns; col++ {
+		depth := 0
+		majority, majorityCount := "-", 0
+		for base, count := range counts[col] {
+			depth += count
+			// break ties by base so the result doesn't depend on map order
+			if count > majorityCount || (count == majorityCount && base < majority) {
+				majority, majorityCount = base, count
+			}
+		}
+
+		entropy := 0.0
+		for _, count := range counts[col] {
+			p := float64(count) / float64(depth)
+			entropy -= p * math.Log2(p)
+		}
+
+		gaps := nbSeqs - depth
+		consensusBase := consensusColumns[col]
+		agree := gaps
+		if consensusBase != "-" {
+			agree = counts[col][consensusBase]
+		}
+
+		stats[col] = ColumnStats{
+			Column:             col,
+			Depth:              depth,
+			Gaps:               gaps,
+			GapFraction:        fraction(gaps, nbSeqs),
+			Entropy:            math.Abs(entropy),
+			MajorityBase:       majority,
+			MajorityFrequency:  fraction(majorityCount, depth),
+			ConsensusBase:      consensusBase,
+			ConsensusAgreement: fraction(agree, nbSeqs),
+		}
+	}
+
+	return stats
+}
+
+func fraction(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// WriteColumnStatsTSV writes the column statistics as a tab separated table with a header line,
+// columns are 1-based
+func WriteColumnStatsTSV(w io.Writer, stats []ColumnStats) error {
+	_, err := fmt.Fprintln(w, "column\tdepth\tgaps\tgap_fraction\tentropy\tmajority\tmajority_freq\tconsensus\tconsensus_agreement")
+	if err != nil {
+		return err
+	}
+	for _, s := range stats {
+		_, err = fmt.Fprintf(w, "%d\t%d\t%d\t%.4f\t%.4f\t%s\t%.4f\t%s\t%.4f\n", s.Column+1, s.Depth, s.Gaps,
+			s.GapFraction, s.Entropy, s.MajorityBase, s.MajorityFrequency, s.ConsensusBase, s.ConsensusAgreement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPoaGraph_ColumnStatistics(t *testing.T) {
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGT", "base", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACT", "new"))
+
+	// ACGT
+	// AC-T
+	stats := g.ColumnStatistics()
+	assert.Equal(t, 4, len(stats))
+
+	assert.Equal(t, 2, stats[0].Depth)
+	assert.Equal(t, 0, stats[0].Gaps)
+	assert.Equal(t, "A", stats[0].MajorityBase)
+	assert.Equal(t, 1.0, stats[0].MajorityFrequency)
+	assert.Equal(t, 0.0, stats[0].Entropy)
+	assert.Equal(t, 1.0, stats[0].ConsensusAgreement)
+
+	assert.Equal(t, 1, stats[2].Depth)
+	assert.Equal(t, 1, stats[2].Gaps)
+	assert.Equal(t, 0.5, stats[2].GapFraction)
+	assert.Equal(t, "G", stats[2].ConsensusBase)
+	assert.Equal(t, 0.5, stats[2].ConsensusAgreement)
+
+	g = PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGT", "s1", true)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACCT", "s2"))
+	stats = g.ColumnStatistics()
+	assert.Equal(t, 4, len(stats))
+	assert.InDelta(t, 1.0, stats[2].Entropy, 1e-9)
+	assert.Equal(t, 0.5, stats[2].MajorityFrequency)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteColumnStatsTSV(&buf, stats))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.True(t, strings.HasPrefix(lines[3], "3\t2\t0\t"))
+}
+
+func TestPoaGraph_ColumnStatisticsEmpty(t *testing.T) {
+	// a new graph and one whose only sequence was removed have no columns
+	removed := PoaGraphConstruct()
+	_, _ = removed.AddBaseSequence("ACGT", "base", true)
+	assert.Nil(t, removed.RemoveSequence("base"))
+	for _, g := range []*PoaGraph{PoaGraphConstruct(), removed} {
+		assert.Equal(t, 0, g.NbNodes())
+		assert.Equal(t, []ColumnStats{}, g.ColumnStatistics())
+		path, bases, _ := g.consensus(nil)
+		assert.Equal(t, 0, len(path)+len(bases))
+	}
+}
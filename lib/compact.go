@@ -0,0 +1,122 @@
+package PoaGo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompactSegment : an unbranched chain of nodes merged into one segment, named by its first node
+type CompactSegment struct {
+	Id      int
+	Seq     string
+	NodeIds []int
+}
+
+// CompactPath : the segments a sequence goes through
+type CompactPath struct {
+	Name     string
+	Segments []int // ids of the segments
+}
+
+// CompactGraph : the graph with its unbranched chains merged, for export. Links are pairs of segment ids
+type CompactGraph struct {
+	Segments []CompactSegment
+	Links    [][2]int
+	Paths    []CompactPath
+}
+
+// Compact merges the unbranched chains of the graph into segments: a node is merged with the next one
+// when it is the only node after it and the next one has no other node before it, and no sequence ends
+// at the first or starts at the second. The graph itself isn't changed
+func (self *PoaGraph) Compact() *CompactGraph {
+	self.ensureSorted()
+
+	paths := make([][]int, len(self.records))
+	startsAt := make([]bool, len(self.nodes))
+	endsAt := make([]bool, len(self.nodes))
+	for i := range self.records {
+		paths[i] = self.pathIds(i)
+		if len(paths[i]) > 0 {
+			startsAt[paths[i][0]] = true
+			endsAt[paths[i][len(paths[i])-1]] = true
+		}
+	}
+	// the node merged after nodeId, -1 if the chain ends there
+	nextInChain := func(nodeId int) int {
+		node := self.nodes[nodeId]
+		if node.OutDegree() != 1 || endsAt[nodeId] {
+			return -1
+		}
+		for next := range node.outEdges {
+			if self.nodes[next].InDegree() == 1 && !startsAt[next] {
+				return next
+			}
+		}
+		return -1
+	}
+
+	cg := &CompactGraph{Segments: make([]CompactSegment, 0), Links: make([][2]int, 0), Paths: make([]CompactPath, 0)}
+	segmentOf := make([]int, len(self.nodes))
+	for i := range segmentOf {
+		segmentOf[i] = -1
+	}
+	// in topological order the first node of a chain comes before the others
+	for _, nodeId := range self.nodeList {
+		if segmentOf[nodeId] >= 0 {
+			continue
+		}
+		segment := CompactSegment{Id: nodeId, NodeIds: make([]int, 0)}
+		var seq strings.Builder
+		for cur := nodeId; cur >= 0; cur = nextInChain(cur) {
+			segmentOf[cur] = len(cg.Segments)
+			segment.NodeIds = append(segment.NodeIds, cur)
+			seq.WriteString(self.nodes[cur].base)
+		}
+		segment.Seq = seq.String()
+		cg.Segments = append(cg.Segments, segment)
+	}
+
+	for _, segment := range cg.Segments {
+		last := segment.NodeIds[len(segment.NodeIds)-1]
+		for _, next := range sortedKeys(self.nodes[last].outEdges) {
+			cg.Links = append(cg.Links, [2]int{segment.Id, cg.Segments[segmentOf[next]].Id})
+		}
+	}
+
+	for i, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		segments := make([]int, 0)
+		for j, nodeId := range path {
+			if j == 0 || segmentOf[nodeId] != segmentOf[path[j-1]] {
+				segments = append(segments, cg.Segments[segmentOf[nodeId]].Id)
+			}
+		}
+		cg.Paths = append(cg.Paths, CompactPath{Name: self.displayName(i), Segments: segments})
+	}
+	return cg
+}
+
+// WriteGFA writes the compacted graph in GFA 1, like PoaGraph.WriteGFA with a segment per chain
+func (self *CompactGraph) WriteGFA(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "H\tVN:Z:1.0%s\n", Program.gfaTags())
+	for _, segment := range self.Segments {
+		fmt.Fprintf(bw, "S\t%d\t%s\n", segment.Id, segment.Seq)
+	}
+	for _, link := range self.Links {
+		fmt.Fprintf(bw, "L\t%d\t+\t%d\t+\t0M\n", link[0], link[1])
+	}
+	for _, path := range self.Paths {
+		steps := make([]string, len(path.Segments))
+		for j, segmentId := range path.Segments {
+			steps[j] = fmt.Sprintf("%d+", segmentId)
+		}
+		fmt.Fprintf(bw, "P\t%s\t%s\t*\n", path.Name, strings.Join(steps, ","))
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,41 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPoaGraph_Compact(t *testing.T) {
+	g := writerTestGraph()
+	cg := g.Compact()
+	assert.Equal(t, []CompactSegment{{Id: 0, Seq: "AC", NodeIds: []int{0, 1}}, {Id: 2, Seq: "G", NodeIds: []int{2}},
+		{Id: 3, Seq: "T", NodeIds: []int{3}}}, cg.Segments)
+	assert.Equal(t, [][2]int{{0, 2}, {0, 3}, {2, 3}}, cg.Links)
+	assert.Equal(t, []CompactPath{{Name: "base", Segments: []int{0, 2, 3}}, {Name: "new", Segments: []int{0, 3}}}, cg.Paths)
+
+	var buf bytes.Buffer
+	assert.Nil(t, cg.WriteGFA(&buf))
+	assert.Equal(t, "H\tVN:Z:1.0\tpn:Z:PoaGo\tvn:Z:NOTSET\nS\t0\tAC\nS\t2\tG\nS\t3\tT\n"+
+		"L\t0\t+\t2\t+\t0M\nL\t0\t+\t3\t+\t0M\nL\t2\t+\t3\t+\t0M\n"+
+		"P\tbase\t0+,2+,3+\t*\nP\tnew\t0+,3+\t*\n", buf.String())
+}
+
+func TestPoaGraph_CompactStartsAndEnds(t *testing.T) {
+	// a sequence starting or ending inside a chain splits it
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("ACGTACGT", "base", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "GTAC", "inner"))
+	cg := g.Compact()
+	seqs := make([]string, len(cg.Segments))
+	for i, segment := range cg.Segments {
+		seqs[i] = segment.Seq
+	}
+	assert.Equal(t, []string{"AC", "GTAC", "GT"}, seqs)
+	assert.Equal(t, []CompactPath{{Name: "base", Segments: []int{0, 2, 6}}, {Name: "inner", Segments: []int{2}}}, cg.Paths)
+
+	single := PoaGraphConstruct()
+	single.AddBaseSequence("ACGT", "one", true)
+	assert.Equal(t, []CompactSegment{{Id: 0, Seq: "ACGT", NodeIds: []int{0, 1, 2, 3}}}, single.Compact().Segments)
+}
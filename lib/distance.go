@@ -0,0 +1,114 @@
+package PoaGo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the set of nodes along the path of the i-th sequence
+func (self *PoaGraph) pathNodes(i int) map[int]bool {
+	nodes := make(map[int]bool)
+	for nodeId := self.records[i].start; nodeId >= 0; nodeId = self.nodes[nodeId].NextNode(i) {
+		nodes[nodeId] = true
+	}
+	return nodes
+}
+
+// counts the positions where two paths share a node (matches) and where a node of the first path
+// is aligned to a node of the second (mismatches)
+func (self *PoaGraph) comparePaths(a, b map[int]bool) (int, int) {
+	matches, mismatches := 0, 0
+	for nodeId := range a {
+		if b[nodeId] {
+			matches += 1
+			continue
+		}
+		for _, other := range self.nodes[nodeId].alignedTo {
+			if b[other] {
+				mismatches += 1
+				break
+			}
+		}
+	}
+	return matches, mismatches
+}
+
+// IdentityMatrix computes the pairwise identity between all sequences in the graph from the nodes
+// along their paths, without building the alignment strings. Positions where both sequences go
+// through the same node are identical, positions where they go through aligned nodes are mismatches
+// and gaps are ignored. Returns the sequence names and the identity of each pair, pairs without any
+// aligned position have an identity of 0
+func (self *PoaGraph) IdentityMatrix() ([]string, [][]float64) {
+	nbSeqs := len(self.records)
+	names := make([]string, nbSeqs)
+	paths := make([]map[int]bool, nbSeqs)
+	for i := 0; i < nbSeqs; i++ {
+		names[i] = self.displayName(i)
+		paths[i] = self.pathNodes(i)
+	}
+
+	identity := make([][]float64, nbSeqs)
+	for i := range identity {
+		identity[i] = make([]float64, nbSeqs)
+		identity[i][i] = 1.0
+	}
+	for i := 0; i < nbSeqs; i++ {
+		for j := i + 1; j < nbSeqs; j++ {
+			matches, mismatches := self.comparePaths(paths[i], paths[j])
+			identity[i][j] = fraction(matches, matches+mismatches)
+			identity[j][i] = identity[i][j]
+		}
+	}
+	return names, identity
+}
+
+// DistanceMatrix is 1 - IdentityMatrix
+func (self *PoaGraph) DistanceMatrix() ([]string, [][]float64) {
+	names, identity := self.IdentityMatrix()
+	for i := range identity {
+		for j := range identity[i] {
+			identity[i][j] = 1.0 - identity[i][j]
+		}
+	}
+	return names, identity
+}
+
+// WritePhylipDistance writes a square matrix in PHYLIP format, names longer than 10 characters
+// are written in full (relaxed PHYLIP)
+func WritePhylipDistance(w io.Writer, names []string, matrix [][]float64) error {
+	if _, err := fmt.Fprintf(w, "%5d\n", len(names)); err != nil {
+		return err
+	}
+	for i, name := range names {
+		line := fmt.Sprintf("%-10s", name)
+		if len(name) >= 10 {
+			line = name + " "
+		}
+		for _, d := range matrix[i] {
+			line += fmt.Sprintf(" %.6f", d)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMatrixTSV writes a square matrix as a tab separated table with the names as header and
+// first column
+func WriteMatrixTSV(w io.Writer, names []string, matrix [][]float64) error {
+	if _, err := fmt.Fprintln(w, "\t"+strings.Join(names, "\t")); err != nil {
+		return err
+	}
+	for i, name := range names {
+		fields := []string{name}
+		for _, d := range matrix[i] {
+			fields = append(fields, fmt.Sprintf("%.6f", d))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPoaGraph_IdentityMatrix(t *testing.T) {
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGTACGT", "s1", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTACGT", "s2"))
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGAACGT", "s3"))
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTCGT", "s4"))
+
+	names, identity := g.IdentityMatrix()
+	assert.Equal(t, []string{"s1", "s2", "s3", "s4"}, names)
+	assert.Equal(t, 1.0, identity[0][1])
+	assert.Equal(t, 7.0/8.0, identity[0][2])
+	assert.Equal(t, identity[0][2], identity[2][0])
+	// the deletion isn't counted
+	assert.Equal(t, 1.0, identity[0][3])
+	assert.Equal(t, 6.0/7.0, identity[2][3])
+
+	_, distance := g.DistanceMatrix()
+	assert.Equal(t, 0.0, distance[1][1])
+	assert.InDelta(t, 1.0/8.0, distance[0][2], 1e-9)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WritePhylipDistance(&buf, names, distance))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, "4", strings.TrimSpace(lines[0]))
+	assert.True(t, strings.HasPrefix(lines[1], "s1         0.000000"))
+
+	buf.Reset()
+	assert.Nil(t, WriteMatrixTSV(&buf, names, identity))
+	lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Equal(t, "\ts1\ts2\ts3\ts4", lines[0])
+	assert.Equal(t, 5, len(lines))
+}
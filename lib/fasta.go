@@ -1,105 +1,205 @@
 package PoaGo
 
-// adopted from https://raw.githubusercontent.com/drio/drio.go/master/bio/fasta/fasta.go
+// originally adopted from https://raw.githubusercontent.com/drio/drio.go/master/bio/fasta/fasta.go
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 )
 
-// Record contains the data from a fasta fastq record
-type record struct {
-	Name, Seq, Qual string
+// Record contains the data from a fasta or fastq record, Qual is empty for fasta
+type Record struct {
+	Name        string // header up to the first whitespace
+	Description string // rest of the header
+	Seq         string
+	Qual        string
+	Line        int // line number of the header
 }
 
-// FqReader holds all the necessary fields that will be use during the processing
-// of a fasta fastq file
-type FqReader struct {
-	Reader          *bufio.Reader
-	last, seq, qual []byte // last line processed, temporary seq and qual values
-	finished        bool
-	rec             record
+// IsFastq returns true if the record has qualities
+func (self Record) IsFastq() bool {
+	return len(self.Qual) > 0
 }
 
-// iterLines iterates over the lines of a reader
-func (fq *FqReader) iterLines() ([]byte, bool) {
-	line, err := fq.Reader.ReadSlice('\n')
-	if err != nil {
-		if err == io.EOF {
-			return line, true
-		} else {
-			panic(err)
+// ParseError : malformed fasta or fastq input
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (self *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", self.Line, self.Msg)
+}
+
+// FastxReader reads fasta and fastq records from a stream. Lines can be of any length, CRLF line
+// endings and a missing newline at the end of the input are handled, fastq can be multi-line
+type FastxReader struct {
+	reader *bufio.Reader
+	line   int    // number of lines read so far
+	header []byte // header of the next record, already read
+	err    error  // sticky error, io.EOF when done
+}
+
+func NewFastxReader(r io.Reader) *FastxReader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &FastxReader{reader: br}
+}
+
+// reads a line without the line ending, returns io.EOF when there are no more lines
+func (self *FastxReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := self.reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(line) > 0 {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	self.line += 1
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, nil
+}
+
+// reads the next non-empty line
+func (self *FastxReader) readNonEmpty() ([]byte, error) {
+	for {
+		line, err := self.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
 		}
 	}
-	return line, false
 }
 
-var space = []byte(" ")
-
-// Iter iterates over the records in a fasta fastq file
-// Example (number of reacords and sequence/qual lenghts):
-//
-// fp, r := files.Xopen("-")
-// defer fp.Close()
-// n, sLen, qLen := 0, int64(0), int64(0)
-// var fqr fasta.FqReader
-// fqr.Reader = r // Any reader you want ..
-// for r, done := fqr.iter(); !done; r, done = fqr.iter() {
-// 	n += 1
-//  sLen += int64(len(r.seq))
-//  qLen += int64(len(r.qual))
-// }
-//
-func (fq *FqReader) Iter() (record, bool) {
-	if fq.finished {
-		return fq.rec, fq.finished
+func (self *FastxReader) fail(err error) (Record, error) {
+	self.err = err
+	return Record{}, err
+}
+
+// Next returns the next record, at the end of the input the error is io.EOF. Malformed input gives
+// a *ParseError with the line number
+func (self *FastxReader) Next() (Record, error) {
+	if self.err != nil {
+		return Record{}, self.err
+	}
+
+	header := self.header
+	self.header = nil
+	if header == nil {
+		line, err := self.readNonEmpty()
+		if err != nil {
+			return self.fail(err)
+		}
+		header = line
+	}
+	if header[0] != '>' && header[0] != '@' {
+		return self.fail(&ParseError{Line: self.line, Msg: "expected a header starting with > or @"})
+	}
+
+	rec := Record{Line: self.line}
+	fields := bytes.Fields(header[1:])
+	if len(fields) > 0 {
+		rec.Name = string(fields[0])
+		rec.Description = string(bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(header[1:]), fields[0])))
 	}
-	// Read the seq id (fasta or fastq)
-	if fq.last == nil {
-		for l, done := fq.iterLines(); !done; l, done = fq.iterLines() {
-			if l[0] == '>' || l[0] == '@' { // read id
-				fq.last = l[0 : len(l)-1]
-				break
+
+	// sequence lines, up to the next header (fasta) or the + line (fastq)
+	isFastq := header[0] == '@'
+	var seq []byte
+	for {
+		line, err := self.readLine()
+		if err == io.EOF {
+			if isFastq {
+				return self.fail(&ParseError{Line: self.line, Msg: fmt.Sprintf("fastq record %v has no + line", rec.Name)})
 			}
+			rec.Seq = string(seq)
+			self.err = io.EOF
+			return rec, nil
 		}
-		if fq.last == nil { // We couldn't find a valid record, no more data in file
-			fq.finished = true
-			return fq.rec, fq.finished
+		if err != nil {
+			return self.fail(err)
 		}
-	}
-	fq.rec.Name = string(bytes.SplitN(fq.last, space, 1)[0])
-	fq.rec.Name = fq.rec.Name[1:]  // drop leading > or @
-	fq.last = nil
-
-	// Now read the sequence
-	fq.seq = fq.seq[:0]
-	for l, done := fq.iterLines(); !done; l, done = fq.iterLines() {
-		c := l[0]
-		if c == '+' || c == '>' || c == '@' {
-			fq.last = l[0 : len(l)-1]
+		if !isFastq && len(line) > 0 && line[0] == '>' {
+			self.header = line
+			rec.Seq = string(seq)
+			return rec, nil
+		}
+		if isFastq && len(line) > 0 && line[0] == '+' {
 			break
 		}
-		fq.seq = append(fq.seq, l[0:len(l)-1]...)
+		seq = append(seq, bytes.TrimSpace(line)...)
 	}
-	fq.rec.Seq = string(fq.seq)
+	rec.Seq = string(seq)
 
-	if fq.last != nil { // There are more lines
-		if fq.last[0] != '+' { // fasta record
-			return fq.rec, fq.finished
+	// quality lines, these can start with @ or + so read until there are as many as bases
+	var qual []byte
+	for len(qual) < len(seq) {
+		line, err := self.readLine()
+		if err == io.EOF {
+			return self.fail(&ParseError{Line: self.line, Msg: fmt.Sprintf("fastq record %v has truncated qualities", rec.Name)})
 		}
-		leng := 0
-		fq.qual = fq.qual[:0]
-		for l, done := fq.iterLines(); !done; l, done = fq.iterLines() {
-			fq.qual = append(fq.qual, l[0:len(l)-1]...)
-			leng += len(l)
-			if leng >= len(fq.seq) { // we have read enough quality
-				fq.last = nil
-				fq.rec.Qual = string(fq.qual)
-				return fq.rec, fq.finished
-			}
+		if err != nil {
+			return self.fail(err)
 		}
-		fq.finished = true
-		fq.rec.Qual = string(fq.qual)
+		qual = append(qual, bytes.TrimSpace(line)...)
+	}
+	if len(qual) != len(seq) {
+		return self.fail(&ParseError{Line: self.line, Msg: fmt.Sprintf("fastq record %v has %d bases and %d qualities",
+			rec.Name, len(seq), len(qual))})
+	}
+	rec.Qual = string(qual)
+	return rec, nil
+}
+
+// FqReader : iterator interface kept for older callers, use FastxReader which reports errors
+type FqReader struct {
+	Reader *bufio.Reader
+	fastx  *FastxReader
+}
+
+// Iter returns the next record and false, or true when there are no more records. Malformed input
+// panics
+func (fq *FqReader) Iter() (Record, bool) {
+	if fq.fastx == nil {
+		fq.fastx = NewFastxReader(fq.Reader)
+	}
+	rec, err := fq.fastx.Next()
+	if errors.Is(err, io.EOF) {
+		return rec, true
+	}
+	if err != nil {
+		panic(err)
+	}
+	return rec, false
+}
+
+// WriteFasta writes a single fasta record
+func WriteFasta(w io.Writer, name, seq string) error {
+	_, err := fmt.Fprintf(w, ">%s\n%s\n", name, seq)
+	return err
+}
+
+// WriteFastq writes a single fastq record, the header is the name followed by the description
+func WriteFastq(w io.Writer, name, description, seq, qual string) error {
+	header := name
+	if description != "" {
+		header += " " + description
 	}
-	return fq.rec, fq.finished // incomplete fastq quality, return what we have
+	_, err := fmt.Fprintf(w, "@%s\n%s\n+\n%s\n", header, seq, qual)
+	return err
 }
@@ -0,0 +1,142 @@
+package PoaGo
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func readAllRecords(t *testing.T, input string) ([]Record, error) {
+	r := NewFastxReader(strings.NewReader(input))
+	records := make([]Record, 0)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestFastxReader_Fasta(t *testing.T) {
+	records, err := readAllRecords(t, ">seq1 first read\nACGT\nAC\n\n>seq2\tdesc\r\nTTGA\r\n>empty\n>last\nGG")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(records))
+	assert.Equal(t, Record{Name: "seq1", Description: "first read", Seq: "ACGTAC", Line: 1}, records[0])
+	assert.Equal(t, Record{Name: "seq2", Description: "desc", Seq: "TTGA", Line: 5}, records[1])
+	assert.Equal(t, "", records[2].Seq)
+	// no trailing newline
+	assert.Equal(t, "GG", records[3].Seq)
+	assert.False(t, records[3].IsFastq())
+}
+
+func TestFastxReader_Fastq(t *testing.T) {
+	input := "@r1 x=1\nACGT\n+\n@@+I\n@r2\nAC\nGT\n+r2\nII\nI@\n"
+	records, err := readAllRecords(t, input)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, Record{Name: "r1", Description: "x=1", Seq: "ACGT", Qual: "@@+I", Line: 1}, records[0])
+	assert.Equal(t, Record{Name: "r2", Seq: "ACGT", Qual: "III@", Line: 5}, records[1])
+	assert.True(t, records[1].IsFastq())
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteFastq(&buf, "r1", "x=1", "ACGT", "@@+I"))
+	assert.Nil(t, WriteFastq(&buf, "r2", "", "ACGT", "III@"))
+	again, err := readAllRecords(t, buf.String())
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Qual, again[0].Qual)
+	assert.Equal(t, records[1].Seq, again[1].Seq)
+}
+
+func TestFastxReader_LongLines(t *testing.T) {
+	seq := strings.Repeat("ACGT", 10000)
+	records, err := readAllRecords(t, ">long\n"+seq+"\n")
+	assert.Nil(t, err)
+	assert.Equal(t, seq, records[0].Seq)
+}
+
+func TestFastxReader_Errors(t *testing.T) {
+	cases := map[string]int{
+		"ACGT\n>seq\nAC\n":          1,
+		"@r1\nACGT\n+\nII\n":        4,
+		"@r1\nACGT\n+\nIIIII\n":     4,
+		"@r1\nACGT\n":               2,
+		">ok\nAC\n\n\nnot a header": 0,
+	}
+	for input, line := range cases {
+		_, err := readAllRecords(t, input)
+		if line == 0 {
+			assert.Nil(t, err, input)
+			continue
+		}
+		var pe *ParseError
+		assert.True(t, errors.As(err, &pe), input)
+		if pe != nil {
+			assert.Equal(t, line, pe.Line, input)
+		}
+	}
+}
+
+func FuzzFastxReader(f *testing.F) {
+	f.Add(">seq1 desc\nACGT\n>seq2\nTT\n")
+	f.Add("@r1\nACGT\n+\n@@@@\n@r2\nA\n+\n+\n")
+	f.Add(">a\r\nAC\r\n")
+	f.Add("\n\n>")
+	f.Fuzz(func(t *testing.T, input string) {
+		r := NewFastxReader(strings.NewReader(input))
+		for i := 0; i <= len(input); i++ {
+			rec, err := r.Next()
+			if err != nil {
+				// errors are sticky
+				_, again := r.Next()
+				if again != err {
+					t.Fatalf("error changed from %v to %v", err, again)
+				}
+				return
+			}
+			if rec.IsFastq() && len(rec.Qual) != len(rec.Seq) {
+				t.Fatalf("record %v has %d bases and %d qualities", rec.Name, len(rec.Seq), len(rec.Qual))
+			}
+		}
+		t.Fatal("reader didn't finish")
+	})
+}
+
+func FuzzFastxRoundTrip(f *testing.F) {
+	f.Add("seq1", "a description", "ACGTN", "IIII#")
+	f.Add("r", "", "", "")
+	f.Fuzz(func(t *testing.T, name, description, seq, qual string) {
+		// only what the formats can represent
+		if strings.IndexFunc(name, unicode.IsSpace) >= 0 || name == "" ||
+			strings.ContainsAny(description, "\r\n") || strings.TrimSpace(description) != description ||
+			strings.IndexFunc(seq+qual, unicode.IsSpace) >= 0 || len(seq) != len(qual) {
+			t.Skip()
+		}
+		var buf bytes.Buffer
+		if qual == "" {
+			if strings.HasPrefix(seq, ">") {
+				t.Skip()
+			}
+			WriteFasta(&buf, name+" "+description, seq)
+		} else {
+			if strings.HasPrefix(seq, "+") {
+				t.Skip()
+			}
+			WriteFastq(&buf, name, description, seq, qual)
+		}
+		rec, err := NewFastxReader(&buf).Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Name != name || rec.Seq != seq || rec.Qual != qual || strings.TrimSpace(rec.Description) != description {
+			t.Fatalf("round trip changed the record %q %q %q %q -> %+v", name, description, seq, qual, rec)
+		}
+	})
+}
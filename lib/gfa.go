@@ -0,0 +1,60 @@
+package PoaGo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ids of the neighbors of a node, sorted so the output doesn't depend on the map order
+func sortedKeys(edges map[int]*Edge) []int {
+	keys := make([]int, 0, len(edges))
+	for k := range edges {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// pathIds returns the node ids along the path of the i-th sequence, in order
+func (self *PoaGraph) pathIds(i int) []int {
+	path := make([]int, 0, len(self.records[i].Seq))
+	for nodeId := self.records[i].start; nodeId >= 0; nodeId = self.nodes[nodeId].NextNode(i) {
+		path = append(path, nodeId)
+	}
+	return path
+}
+
+// WriteGFA writes the graph in GFA 1: one segment per node named by the node id, one link per edge
+// and one path per sequence. Sequences added as their reverse complement get a path named with the
+// reverse suffix
+func (self *PoaGraph) WriteGFA(w io.Writer) error {
+	self.ensureSorted()
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "H\tVN:Z:1.0%s\n", Program.gfaTags())
+	for _, nodeId := range self.nodeList {
+		fmt.Fprintf(bw, "S\t%d\t%s\n", nodeId, self.nodes[nodeId].base)
+	}
+	for _, nodeId := range self.nodeList {
+		node := self.nodes[nodeId]
+		for _, next := range sortedKeys(node.outEdges) {
+			fmt.Fprintf(bw, "L\t%d\t+\t%d\t+\t0M\n", nodeId, next)
+		}
+	}
+	for i := range self.records {
+		path := self.pathIds(i)
+		if len(path) == 0 {
+			// everything the sequence went through was pruned
+			continue
+		}
+		steps := make([]string, len(path))
+		for j, nodeId := range path {
+			steps[j] = fmt.Sprintf("%d+", nodeId)
+		}
+		fmt.Fprintf(bw, "P\t%s\t%s\t*\n", self.displayName(i), strings.Join(steps, ","))
+	}
+	return bw.Flush()
+}
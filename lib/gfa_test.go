@@ -0,0 +1,31 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPoaGraph_WriteGFA(t *testing.T) {
+	g := writerTestGraph()
+	var buf bytes.Buffer
+	assert.Nil(t, g.WriteGFA(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	assert.Equal(t, "H\tVN:Z:1.0\tpn:Z:PoaGo\tvn:Z:NOTSET", lines[0])
+	segments, links, paths := 0, 0, make([]string, 0)
+	for _, line := range lines[1:] {
+		switch line[0] {
+		case 'S':
+			segments += 1
+		case 'L':
+			links += 1
+		case 'P':
+			paths = append(paths, line)
+		}
+	}
+	assert.Equal(t, g.NbNodes(), segments)
+	assert.Equal(t, g.NbEdges(), links)
+	assert.Equal(t, []string{"P\tbase\t0+,1+,2+,3+\t*", "P\tnew\t0+,1+,3+\t*"}, paths)
+}
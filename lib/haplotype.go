@@ -0,0 +1,108 @@
+package PoaGo
+
+// ConsensusCluster : a consensus path through the graph together with the reads that support it
+type ConsensusCluster struct {
+	Path    []int    // node ids along the consensus
+	Bases   []string // bases along the consensus
+	Labels  []string // labels of the sequences assigned to this consensus
+	Support int      // number of sequences assigned to this consensus
+}
+
+func (self *ConsensusCluster) Sequence() string {
+	seq := make([]byte, 0, len(self.Bases))
+	for _, b := range self.Bases {
+		seq = append(seq, b...)
+	}
+	return string(seq)
+}
+
+func (self *PoaGraph) SetMaxFraction(maxFraction float64) {
+	self.maxFraction = maxFraction
+}
+
+func (self *PoaGraph) MaxFraction() float64 {
+	return self.maxFraction
+}
+
+// Sequence returns the sequence added to the graph with this label, in the orientation it was added
+func (self *PoaGraph) Sequence(label string) (string, bool) {
+	if rec, ok := self.Record(label); ok {
+		return rec.Seq, true
+	}
+	return "", false
+}
+
+// Calls consensus repeatedly, after each round the labels that have at least maxFraction of their
+// length on the consensus path are assigned to it and excluded from the following rounds. Stops when
+// all labels are assigned or when a round doesn't assign any new labels, the consensus of that round
+// isn't returned
+func (self *PoaGraph) consensusRounds(maxFraction float64) []*ConsensusCluster {
+	clusters := make([]*ConsensusCluster, 0)
+	exclusions := make(labelSet, 0)
+
+	for exclusions.count() < len(self.records) {
+		path, bases, labelLists := self.consensus(exclusions)
+		if len(path) == 0 {
+			break
+		}
+		cluster := &ConsensusCluster{Path: path, Bases: bases, Labels: make([]string, 0)}
+
+		labelCounts := make([]int, len(self.records))
+		// tally up all of the sequences we've seen in this consensus
+		for _, labelList := range labelLists {
+			labelList.forEach(func(seqId int) { labelCounts[seqId] += 1 })
+		}
+
+		assigned := make([]int, 0)
+		for _, rec := range self.records {
+			if exclusions.has(rec.Id) {
+				continue
+			}
+			count := labelCounts[rec.Id]
+			if count > 0 && float64(count) >= maxFraction*float64(len(rec.Seq)) {
+				assigned = append(assigned, rec.Id)
+				cluster.Labels = append(cluster.Labels, rec.Name)
+			}
+		}
+		cluster.Support = len(cluster.Labels)
+
+		if cluster.Support == 0 {
+			// no progress, the remaining labels will never be assigned and no read supports this consensus
+			break
+		}
+		clusters = append(clusters, cluster)
+		for _, seqId := range assigned {
+			exclusions.add(seqId)
+		}
+	}
+
+	return clusters
+}
+
+// HaplotypeClusters separates the sequences in the graph into haplotypes. Each returned cluster is a
+// consensus with the labels of the sequences that have at least maxFraction of their length on it.
+// Clusters supported by fewer than minSupport sequences are dropped, the labels of their sequences
+// are returned as unassigned along with the ones that didn't fit any consensus
+func (self *PoaGraph) HaplotypeClusters(maxFraction float64, minSupport int) ([]*ConsensusCluster, []string) {
+	clusters := make([]*ConsensusCluster, 0)
+	assigned := make(map[string]bool)
+
+	for _, cluster := range self.consensusRounds(maxFraction) {
+		if cluster.Support == 0 || cluster.Support < minSupport {
+			continue
+		}
+		clusters = append(clusters, cluster)
+		for _, label := range cluster.Labels {
+			assigned[label] = true
+		}
+	}
+
+	unassigned := make([]string, 0)
+	for _, label := range self.labels() {
+		if !assigned[label] {
+			unassigned = append(unassigned, label)
+		}
+	}
+
+	return clusters, unassigned
+}
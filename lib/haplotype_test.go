@@ -0,0 +1,53 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPoaGraph_HaplotypeClusters(t *testing.T) {
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	hap1 := "ACGGTCATTGACCTAGGCATTACGGATCCA"
+	hap2 := "ACGGTCATTGTTGAAGGCATTACGGATCCA"
+
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence(hap1, "a1", true)
+	reads := map[string]string{"a2": hap1, "a3": hap1, "b1": hap2, "b2": hap2}
+	for _, label := range []string{"a2", "b1", "a3", "b2"} {
+		g.AddSequenceAlignment(AlignStringToGraph(g, aln, reads[label], label))
+	}
+
+	clusters, unassigned := g.HaplotypeClusters(0.9, 1)
+	assert.Equal(t, 2, len(clusters))
+	assert.Equal(t, 0, len(unassigned))
+	assert.Equal(t, hap1, clusters[0].Sequence())
+	assert.Equal(t, 3, clusters[0].Support)
+	assert.ElementsMatch(t, []string{"a1", "a2", "a3"}, clusters[0].Labels)
+	assert.Equal(t, hap2, clusters[1].Sequence())
+	assert.ElementsMatch(t, []string{"b1", "b2"}, clusters[1].Labels)
+
+	// the second haplotype doesn't have enough reads
+	clusters, unassigned = g.HaplotypeClusters(0.9, 3)
+	assert.Equal(t, 1, len(clusters))
+	assert.ElementsMatch(t, []string{"b1", "b2"}, unassigned)
+
+	// a low fraction puts every read on the first consensus
+	clusters, _ = g.HaplotypeClusters(0.1, 1)
+	assert.Equal(t, 1, len(clusters))
+	assert.Equal(t, 5, clusters[0].Support)
+}
+
+func TestPoaGraph_consensusRoundsNoProgress(t *testing.T) {
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGGTCATTGACCTAGG", "a1", true)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGGTCATTGTTGAAGG", "b1"))
+
+	// no read can have more than its length on a consensus, the round assigning none isn't returned
+	assert.Equal(t, 0, len(g.consensusRounds(1.5)))
+	_, _, nbConsensus := g.AllConsensuses(1.5)
+	assert.Equal(t, 0, nbConsensus)
+	for _, cluster := range g.consensusRounds(0.9) {
+		assert.True(t, cluster.Support > 0)
+	}
+}
@@ -0,0 +1,107 @@
+package PoaGo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// magic bytes of the supported compression formats
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// closes the decompressor and the underlying file
+type decompressedReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (self *decompressedReader) Close() error {
+	var err error
+	for _, c := range self.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Decompress wraps r in a decompressor if it starts with the magic bytes of gzip, bzip2, xz or
+// zstd, otherwise the data is passed through as is
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(xzMagic)) // shorter input just can't be compressed
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(br), nil
+	case bytes.HasPrefix(magic, xzMagic):
+		return xz.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		d, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return br, nil
+	}
+}
+
+// OpenInput opens a file, "-" is stdin, and decompresses it transparently
+func OpenInput(path string) (io.ReadCloser, error) {
+	var fH *os.File
+	if path == "-" {
+		fH = os.Stdin
+	} else {
+		var err error
+		fH, err = os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := Decompress(fH)
+	if err != nil {
+		fH.Close()
+		return nil, err
+	}
+	closers := []io.Closer{fH}
+	if c, ok := r.(io.Closer); ok {
+		closers = append([]io.Closer{c}, closers...)
+	}
+	return &decompressedReader{Reader: r, closers: closers}, nil
+}
+
+// OpenInputs opens all the paths with OpenInput and reads them one after the other, a newline is
+// put between the inputs so a file without a trailing newline doesn't run into the next one
+func OpenInputs(paths []string) (io.ReadCloser, error) {
+	readers := make([]io.Reader, 0, 2*len(paths))
+	closers := make([]io.Closer, 0, len(paths))
+	for i, path := range paths {
+		r, err := OpenInput(path)
+		if err != nil {
+			for _, c := range closers {
+				c.Close()
+			}
+			return nil, err
+		}
+		if i > 0 {
+			readers = append(readers, bytes.NewReader([]byte("\n")))
+		}
+		readers = append(readers, r)
+		closers = append(closers, r)
+	}
+	return &decompressedReader{Reader: io.MultiReader(readers...), closers: closers}, nil
+}
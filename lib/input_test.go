@@ -0,0 +1,63 @@
+package PoaGo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const inputTestFasta = ">seq1\nACGT\n>seq2\nTTGA\n"
+
+func TestDecompress(t *testing.T) {
+	var gz, x, zs bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(inputTestFasta))
+	gw.Close()
+	xw, _ := xz.NewWriter(&x)
+	xw.Write([]byte(inputTestFasta))
+	xw.Close()
+	zw, _ := zstd.NewWriter(&zs)
+	zw.Write([]byte(inputTestFasta))
+	zw.Close()
+
+	for _, data := range [][]byte{[]byte(inputTestFasta), gz.Bytes(), x.Bytes(), zs.Bytes()} {
+		r, ok := Decompress(bytes.NewReader(data))
+		assert.Nil(t, ok)
+		out, ok := io.ReadAll(r)
+		assert.Nil(t, ok)
+		assert.Equal(t, inputTestFasta, string(out))
+	}
+
+	// too short to have a magic number
+	r, ok := Decompress(bytes.NewReader([]byte(">")))
+	assert.Nil(t, ok)
+	out, _ := io.ReadAll(r)
+	assert.Equal(t, ">", string(out))
+}
+
+func TestOpenInputs(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "a.fa")
+	assert.Nil(t, os.WriteFile(plain, []byte(">seq1\nACGT"), 0644))
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(">seq2\nTTGA\n"))
+	gw.Close()
+	compressed := filepath.Join(dir, "b.fa.gz")
+	assert.Nil(t, os.WriteFile(compressed, gz.Bytes(), 0644))
+
+	r, ok := OpenInputs([]string{plain, compressed})
+	assert.Nil(t, ok)
+	out, _ := io.ReadAll(r)
+	assert.Nil(t, r.Close())
+	assert.Equal(t, ">seq1\nACGT\n>seq2\nTTGA\n", string(out))
+
+	_, ok = OpenInputs([]string{plain, filepath.Join(dir, "missing.fa")})
+	assert.NotNil(t, ok)
+}
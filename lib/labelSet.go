@@ -0,0 +1,98 @@
+package PoaGo
+
+import (
+	"math/bits"
+)
+
+// labelSet : set of sequence ids as a bitset, bit i of word i/64 is set if sequence i is in the set.
+// The ids are the dense record ids of the graph so the sets stay small, 10k reads fit in 157 words
+type labelSet []uint64
+
+const labelWordBits = 64
+
+// has returns true if id is in the set
+func (self labelSet) has(id int) bool {
+	word := id / labelWordBits
+	return id >= 0 && word < len(self) && self[word]&(1<<uint(id%labelWordBits)) != 0
+}
+
+// add adds id to the set, growing it if needed
+func (self *labelSet) add(id int) {
+	word := id / labelWordBits
+	for len(*self) <= word {
+		*self = append(*self, 0)
+	}
+	(*self)[word] |= 1 << uint(id%labelWordBits)
+}
+
+// remove removes id from the set
+func (self labelSet) remove(id int) {
+	if word := id / labelWordBits; id >= 0 && word < len(self) {
+		self[word] &^= 1 << uint(id%labelWordBits)
+	}
+}
+
+// deleteId removes id from the set and shifts the ids above it down by one, for when the sequence
+// with this id is removed from the graph
+func (self labelSet) deleteId(id int) {
+	word := id / labelWordBits
+	if id < 0 || word >= len(self) {
+		return
+	}
+	bit := uint(id % labelWordBits)
+	below := self[word] & (1<<bit - 1)
+	above := self[word] >> (bit + 1) << bit
+	self[word] = below | above
+	for i := word + 1; i < len(self); i++ {
+		self[i-1] |= (self[i] & 1) << (labelWordBits - 1)
+		self[i] >>= 1
+	}
+}
+
+// union adds the ids of other to the set
+func (self *labelSet) union(other labelSet) {
+	for len(*self) < len(other) {
+		*self = append(*self, 0)
+	}
+	for i, word := range other {
+		(*self)[i] |= word
+	}
+}
+
+// count returns the number of ids in the set
+func (self labelSet) count() int {
+	n := 0
+	for _, word := range self {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// countExcluding returns the number of ids in the set that aren't in exclusions
+func (self labelSet) countExcluding(exclusions labelSet) int {
+	n := 0
+	for i, word := range self {
+		if i < len(exclusions) {
+			word &^= exclusions[i]
+		}
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// forEach calls f with the ids of the set in increasing order
+func (self labelSet) forEach(f func(id int)) {
+	for i, word := range self {
+		for word != 0 {
+			f(i*labelWordBits + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
+
+// ids returns the ids of the set in increasing order
+func (self labelSet) ids() []int {
+	ids := make([]int, 0, self.count())
+	self.forEach(func(id int) { ids = append(ids, id) })
+	return ids
+}
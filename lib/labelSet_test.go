@@ -0,0 +1,142 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLabelSet(t *testing.T) {
+	s := make(labelSet, 0)
+	assert.False(t, s.has(0))
+	assert.False(t, s.has(-1))
+	for _, id := range []int{130, 3, 64, 3, 0} {
+		s.add(id)
+	}
+	assert.Equal(t, 3, len(s))
+	assert.Equal(t, 4, s.count())
+	assert.Equal(t, []int{0, 3, 64, 130}, s.ids())
+	assert.True(t, s.has(64))
+	assert.False(t, s.has(65))
+	assert.False(t, s.has(1000))
+
+	s.remove(3)
+	s.remove(1000)
+	assert.Equal(t, []int{0, 64, 130}, s.ids())
+
+	exclusions := make(labelSet, 0)
+	exclusions.add(64)
+	assert.Equal(t, 2, s.countExcluding(exclusions))
+	assert.Equal(t, 3, s.countExcluding(nil))
+
+	other := make(labelSet, 0)
+	other.add(200)
+	other.add(1)
+	s.union(other)
+	assert.Equal(t, []int{0, 1, 64, 130, 200}, s.ids())
+}
+
+func TestPoaGraph_EdgeLabels(t *testing.T) {
+	g := writerTestGraph()
+	assert.Equal(t, []string{"base", "new"}, g.EdgeLabels(0, 1))
+	assert.Equal(t, []string{"base"}, g.EdgeLabels(1, 2))
+	assert.Nil(t, g.EdgeLabels(0, 3))
+	assert.Nil(t, g.EdgeLabels(-1, 0))
+}
+
+const benchmarkNbReads = 10000
+
+// the labels as they were stored before, a slice searched linearly
+func benchmarkSliceAdd(ids []int, id int) []int {
+	for _, x := range ids {
+		if x == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func BenchmarkLabelSlice_Add(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		ids := make([]int, 0)
+		for id := 0; id < benchmarkNbReads; id++ {
+			ids = benchmarkSliceAdd(ids, id)
+		}
+	}
+}
+
+func BenchmarkLabelSet_Add(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		s := make(labelSet, 0)
+		for id := 0; id < benchmarkNbReads; id++ {
+			s.add(id)
+		}
+	}
+}
+
+// weight of an edge with all the reads, excluding half of them, as in consensusRounds
+func BenchmarkLabelSlice_CountExcluding(b *testing.B) {
+	ids, exclusions := make([]int, 0), make([]int, 0)
+	for id := 0; id < benchmarkNbReads; id++ {
+		ids = append(ids, id)
+		if id%2 == 0 {
+			exclusions = append(exclusions, id)
+		}
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		weight := 0
+		for _, id := range ids {
+			excluded := false
+			for _, x := range exclusions {
+				if x == id {
+					excluded = true
+					break
+				}
+			}
+			if !excluded {
+				weight += 1
+			}
+		}
+	}
+}
+
+func BenchmarkLabelSet_CountExcluding(b *testing.B) {
+	s, exclusions := make(labelSet, 0), make(labelSet, 0)
+	for id := 0; id < benchmarkNbReads; id++ {
+		s.add(id)
+		if id%2 == 0 {
+			exclusions.add(id)
+		}
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		s.countExcluding(exclusions)
+	}
+}
+
+// a graph of many reads of the same sequence, every edge carries all of them
+func benchmarkGraph(nbReads int) *PoaGraph {
+	g := PoaGraphConstruct()
+	seq := "GATTACAGGCATTCCAGATTACAGGCATTCCA"
+	g.AddBaseSequence(seq, "read0", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	pA := AlignStringToGraph(g, aln, seq, "read")
+	for i := 1; i < nbReads; i++ {
+		g.AddSequenceAlignment(pA)
+	}
+	return g
+}
+
+func BenchmarkPoaGraph_AddSequenceAlignment(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchmarkGraph(1000)
+	}
+}
+
+func BenchmarkPoaGraph_HaplotypeClusters(b *testing.B) {
+	g := benchmarkGraph(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g.HaplotypeClusters(DefaultMaxFraction, 1)
+	}
+}
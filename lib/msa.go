@@ -0,0 +1,252 @@
+package PoaGo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MSA : a multiple sequence alignment, all rows have the same length
+type MSA struct {
+	Names []string
+	Rows  []string
+}
+
+func isGap(c byte) bool {
+	return c == '-' || c == '.' || c == '~'
+}
+
+func (self *MSA) NbColumns() int {
+	if len(self.Rows) == 0 {
+		return 0
+	}
+	return len(self.Rows[0])
+}
+
+// checks that the alignment isn't empty, the names are unique and the rows have the same length
+func (self *MSA) validate() error {
+	if len(self.Rows) == 0 {
+		return errors.New("MSA has no sequences")
+	}
+	seen := make(map[string]bool)
+	for i, name := range self.Names {
+		if seen[name] {
+			return errors.New(fmt.Sprintf("MSA has more than one sequence named %v", name))
+		}
+		seen[name] = true
+		if len(self.Rows[i]) != len(self.Rows[0]) {
+			return errors.New(fmt.Sprintf("MSA row %v has length %d, expected %d", name, len(self.Rows[i]), len(self.Rows[0])))
+		}
+	}
+	return nil
+}
+
+// accumulates interleaved "name sequence" lines, as in clustal and stockholm files
+type interleavedRows struct {
+	msa   MSA
+	index map[string]int
+}
+
+func (self *interleavedRows) add(name, seq string) {
+	if self.index == nil {
+		self.index = make(map[string]int)
+	}
+	i, ok := self.index[name]
+	if !ok {
+		i = len(self.msa.Names)
+		self.index[name] = i
+		self.msa.Names = append(self.msa.Names, name)
+		self.msa.Rows = append(self.msa.Rows, "")
+	}
+	self.msa.Rows[i] += seq
+}
+
+// ReadAlignedFasta reads an alignment in fasta format, gaps are '-', '.' or '~'
+func ReadAlignedFasta(r io.Reader) (*MSA, error) {
+	msa := &MSA{}
+	fr := NewFastxReader(r)
+	for {
+		rec, err := fr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		msa.Names = append(msa.Names, rec.Name)
+		msa.Rows = append(msa.Rows, rec.Seq)
+	}
+	return msa, msa.validate()
+}
+
+// ReadClustal reads an alignment in CLUSTAL format
+func ReadClustal(r io.Reader) (*MSA, error) {
+	rows := interleavedRows{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	lineNb := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		lineNb += 1
+		if lineNb == 1 {
+			if !strings.HasPrefix(line, "CLUSTAL") && !strings.HasPrefix(line, "MUSCLE") && !strings.HasPrefix(line, "PROBCONS") {
+				return nil, &ParseError{Line: lineNb, Msg: "CLUSTAL file should start with a CLUSTAL header"}
+			}
+			continue
+		}
+		// blank and conservation lines start with whitespace
+		if len(strings.TrimSpace(line)) == 0 || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, &ParseError{Line: lineNb, Msg: "expected a sequence name followed by the aligned sequence"}
+		}
+		rows.add(fields[0], fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &rows.msa, rows.msa.validate()
+}
+
+// ReadStockholm reads the first alignment of a Stockholm file, annotation lines are ignored
+func ReadStockholm(r io.Reader) (*MSA, error) {
+	rows := interleavedRows{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	lineNb := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineNb += 1
+		if lineNb == 1 {
+			if !strings.HasPrefix(line, "# STOCKHOLM") {
+				return nil, &ParseError{Line: lineNb, Msg: "Stockholm file should start with # STOCKHOLM"}
+			}
+			continue
+		}
+		if line == "//" {
+			break
+		}
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, &ParseError{Line: lineNb, Msg: "expected a sequence name followed by the aligned sequence"}
+		}
+		rows.add(fields[0], fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &rows.msa, rows.msa.validate()
+}
+
+// ReadMSA reads an alignment in fasta, clustal or stockholm format, "auto" picks the format from
+// the first line
+func ReadMSA(r io.Reader, format string) (*MSA, error) {
+	br := bufio.NewReader(r)
+	if format == "auto" {
+		format = "fasta"
+		for {
+			peek, err := br.Peek(1)
+			if err != nil || (peek[0] != '\n' && peek[0] != '\r') {
+				break
+			}
+			br.ReadByte()
+		}
+		if head, _ := br.Peek(11); strings.HasPrefix(string(head), "# STOCKHOLM") {
+			format = "stockholm"
+		} else if strings.HasPrefix(string(head), "CLUSTAL") || strings.HasPrefix(string(head), "MUSCLE") ||
+			strings.HasPrefix(string(head), "PROBCONS") {
+			format = "clustal"
+		}
+	}
+
+	switch format {
+	case "fasta":
+		return ReadAlignedFasta(br)
+	case "clustal":
+		return ReadClustal(br)
+	case "stockholm":
+		return ReadStockholm(br)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown MSA format %v, should be fasta, clustal, stockholm or auto", format))
+	}
+}
+
+// PoaGraphFromMSA builds a graph from an existing alignment. Each column gets one node per distinct
+// residue (case insensitive), the nodes of a column are aligned to each other and every row becomes
+// a labelled path. New sequences can then be added with AlignStringToGraph and AddSequenceAlignment
+func PoaGraphFromMSA(msa *MSA) (*PoaGraph, error) {
+	if err := msa.validate(); err != nil {
+		return nil, err
+	}
+	g := PoaGraphConstruct()
+	nColumns := msa.NbColumns()
+
+	// node of each residue in each column
+	columnNodes := make([]map[byte]int, nColumns)
+	for col := 0; col < nColumns; col++ {
+		columnNodes[col] = make(map[byte]int)
+		nodeIds := make([]int, 0)
+		for _, row := range msa.Rows {
+			c := strings.ToUpper(row[col : col+1])[0]
+			if isGap(c) {
+				continue
+			}
+			if _, ok := columnNodes[col][c]; !ok {
+				nodeId := g.AddNode(string(c))
+				columnNodes[col][c] = nodeId
+				nodeIds = append(nodeIds, nodeId)
+			}
+		}
+		for _, nodeId := range nodeIds {
+			for _, other := range nodeIds {
+				if other != nodeId {
+					g.nodes[nodeId].alignedTo = append(g.nodes[nodeId].alignedTo, other)
+				}
+			}
+		}
+	}
+
+	for i, row := range msa.Rows {
+		label := msa.Names[i]
+		seq := make([]byte, 0, len(row))
+		for col := 0; col < nColumns; col++ {
+			if c := strings.ToUpper(row[col : col+1])[0]; !isGap(c) {
+				seq = append(seq, c)
+			}
+		}
+		if len(seq) == 0 {
+			return nil, errors.New(fmt.Sprintf("MSA row %v has no residues", label))
+		}
+		record := g.addRecord(SeqRecord{Name: label, Seq: string(seq)}, false)
+
+		prevId := -1
+		for col := 0; col < nColumns; col++ {
+			c := strings.ToUpper(row[col : col+1])[0]
+			if isGap(c) {
+				continue
+			}
+			nodeId := columnNodes[col][c]
+			g.AddEdge(prevId, nodeId, record.Id)
+			if record.start < 0 {
+				record.start = nodeId
+			}
+			prevId = nodeId
+		}
+	}
+
+	// nodes were added column by column so they are already in topological order, keeping that order
+	// keeps the columns of the alignment
+	g.needSort = false
+	if !g.testSort() {
+		return nil, errors.New("PoaGraphFromMSA: sort failed")
+	}
+	g.updateRanks()
+	return g, nil
+}
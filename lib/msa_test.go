@@ -0,0 +1,76 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const msaTestFasta = ">s1\nAC-GT\n>s2\nACTGT\n>s3\nAC-CT\n"
+
+const msaTestClustal = `CLUSTAL W (1.83) multiple sequence alignment
+
+s1      AC-
+s2      ACT
+s3      AC-
+        **
+
+s1      GT
+s2      GT
+s3      CT
+         *
+`
+
+const msaTestStockholm = `# STOCKHOLM 1.0
+#=GF ID test
+s1 AC-
+s2 ACT
+s3 AC.
+
+s1 GT
+s2 GT
+s3 ct
+#=GC SS_cons .....
+//
+`
+
+func TestReadMSA(t *testing.T) {
+	for _, input := range []string{msaTestFasta, msaTestClustal, msaTestStockholm} {
+		msa, err := ReadMSA(strings.NewReader(input), "auto")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"s1", "s2", "s3"}, msa.Names)
+		assert.Equal(t, 5, msa.NbColumns())
+		assert.Equal(t, "ACTGT", msa.Rows[1])
+	}
+
+	_, err := ReadMSA(strings.NewReader(">s1\nACGT\n>s2\nACG\n"), "fasta")
+	assert.NotNil(t, err)
+	_, err = ReadMSA(strings.NewReader(msaTestFasta), "clustal")
+	assert.NotNil(t, err)
+	_, err = ReadMSA(strings.NewReader(msaTestFasta), "phylip")
+	assert.NotNil(t, err)
+}
+
+func TestPoaGraphFromMSA(t *testing.T) {
+	msa, err := ReadMSA(strings.NewReader(msaTestStockholm), "stockholm")
+	assert.Nil(t, err)
+	g, err := PoaGraphFromMSA(msa)
+	assert.Nil(t, err)
+
+	// one node per residue in each column
+	assert.Equal(t, 6, g.nbNodes)
+	seq, _ := g.Sequence("s3")
+	assert.Equal(t, "ACCT", seq)
+
+	seqNames, alignmentStrings := g.GenerateAlignmentStrings()
+	assert.Equal(t, []string{"s1", "s2", "s3"}, seqNames[:3])
+	assert.Equal(t, []string{"AC-GT", "ACTGT", "AC-CT"}, alignmentStrings[:3])
+
+	// extend the alignment with a new sequence
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACTCT", "s4"))
+	_, alignmentStrings = g.GenerateAlignmentStrings()
+	assert.Equal(t, 5, len(alignmentStrings[3]))
+	assert.Equal(t, "ACTCT", strings.Replace(alignmentStrings[3], "-", "", -1))
+	assert.Equal(t, strings.Replace(alignmentStrings[1], "G", "C", 1), alignmentStrings[3])
+}
@@ -0,0 +1,152 @@
+package PoaGo
+
+import (
+	"sort"
+)
+
+// Keeping the topological order of a sorted graph up to date when a sequence is added, instead of
+// sorting the whole graph again. The new nodes of the sequence are placed right after the node before
+// them on its path, which is where a new base aligned between two nodes belongs, and the edges of the
+// path that still go backwards are fixed with the Pearce-Kelly algorithm (A Dynamic Topological Sort
+// Algorithm for Directed Acyclic Graphs, 2006), which only reorders the nodes between the two ends of
+// the edge that are reachable from them
+
+// repairOrder restores the order after AddSequenceAlignment added the sequence seqId to a sorted graph,
+// nodes with ids from firstNewId on are the new nodes of the sequence. Returns false if the order
+// can't be repaired, the graph then needs a full sort
+func (self *PoaGraph) repairOrder(seqId, firstNewId int) bool {
+	path := self.pathIds(seqId)
+	nbNew := self.nextNodeId - firstNewId
+	nbOld := len(self.nodeList) - nbNew
+
+	// chains of new nodes, each starting after an old node or at the start of the path (-1)
+	chainStart := make(map[int]int)
+	chainNext := make(map[int]int)
+	from := nbOld
+	placed := 0
+	prev := -1
+	for _, nodeId := range path {
+		if nodeId >= firstNewId {
+			placed += 1
+			if prev >= firstNewId {
+				chainNext[prev] = nodeId
+			} else {
+				chainStart[prev] = nodeId
+				if prev < 0 {
+					from = 0
+				} else if r := self.nodes[prev].rank; r < from {
+					from = r
+				}
+			}
+		}
+		prev = nodeId
+	}
+	if placed != nbNew {
+		return false
+	}
+
+	// rebuild the order from the first insertion on
+	suffix := append([]int(nil), self.nodeList[from:nbOld]...)
+	self.nodeList = self.nodeList[:from]
+	emitChain := func(anchor int) {
+		nodeId, ok := chainStart[anchor]
+		for ok {
+			self.nodeList = append(self.nodeList, nodeId)
+			nodeId, ok = chainNext[nodeId]
+		}
+	}
+	emitChain(-1)
+	for _, nodeId := range suffix {
+		self.nodeList = append(self.nodeList, nodeId)
+		emitChain(nodeId)
+	}
+	for r := from; r < len(self.nodeList); r++ {
+		self.nodes[self.nodeList[r]].rank = r
+	}
+
+	low := from
+	for i := 1; i < len(path); i++ {
+		x, y := self.nodes[path[i-1]], self.nodes[path[i]]
+		if x.rank < y.rank {
+			continue
+		}
+		if !self.reorderForEdge(x, y) {
+			return false
+		}
+		if y.rank < low {
+			low = y.rank
+		}
+	}
+
+	self.updateRanksFrom(low)
+	for _, nodeId := range path {
+		// old nodes before the changes may have new in-edges
+		if node := self.nodes[nodeId]; node.rank < low {
+			self.updatePredRanks(node)
+		}
+	}
+	self.needSort = false
+	return true
+}
+
+// reorderForEdge is the Pearce-Kelly repair for an edge x -> y with y before x: the nodes reachable
+// from y that are before x and the nodes reaching x that are after y are moved so the ones reaching x
+// come first, reusing their ranks. Returns false if the edge closes a cycle. Only the ranks and
+// nodeList are updated, the caller updates predRanks
+func (self *PoaGraph) reorderForEdge(x, y *Node) bool {
+	lower, upper := y.rank, x.rank
+
+	forward := make([]*Node, 0)
+	seen := map[int]bool{y.id: true}
+	stack := []*Node{y}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		forward = append(forward, node)
+		for neighbor := range node.outEdges {
+			next := self.nodes[neighbor]
+			if next.rank == upper {
+				return false
+			}
+			if next.rank < upper && !seen[neighbor] {
+				seen[neighbor] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+
+	backward := make([]*Node, 0)
+	seen = map[int]bool{x.id: true}
+	stack = append(stack, x)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		backward = append(backward, node)
+		for neighbor := range node.inEdges {
+			prev := self.nodes[neighbor]
+			if prev.rank > lower && !seen[neighbor] {
+				seen[neighbor] = true
+				stack = append(stack, prev)
+			}
+		}
+	}
+
+	byRank := func(nodes []*Node) {
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].rank < nodes[j].rank })
+	}
+	byRank(backward)
+	byRank(forward)
+	ranks := make([]int, 0, len(backward)+len(forward))
+	for _, node := range backward {
+		ranks = append(ranks, node.rank)
+	}
+	for _, node := range forward {
+		ranks = append(ranks, node.rank)
+	}
+	sort.Ints(ranks)
+	for i, node := range append(backward, forward...) {
+		node.rank = ranks[i]
+		self.nodeList[ranks[i]] = node.id
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// a copy of seq with random substitutions, insertions and deletions
+func mutateSequence(r *rand.Rand, seq string, rate float64) string {
+	bases := "ACGT"
+	var b strings.Builder
+	for i := 0; i < len(seq); i++ {
+		switch x := r.Float64(); {
+		case x < rate/3:
+			b.WriteByte(bases[r.Intn(4)])
+		case x < 2*rate/3:
+			b.WriteByte(seq[i])
+			b.WriteByte(bases[r.Intn(4)])
+		case x < rate:
+		default:
+			b.WriteByte(seq[i])
+		}
+	}
+	return b.String()
+}
+
+func randomBases(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "ACGT"[r.Intn(4)]
+	}
+	return string(b)
+}
+
+// the order and the cached ranks are what a full update would give
+func assertOrder(t *testing.T, g *PoaGraph) {
+	assert.False(t, g.needSort)
+	assert.True(t, g.testSort())
+	assert.Equal(t, g.nbNodes, len(g.nodeList))
+	for rank, nodeId := range g.nodeList {
+		node := g.nodes[nodeId]
+		assert.Equal(t, rank, node.rank)
+		predRanks := append([]int(nil), node.predRanks...)
+		g.updatePredRanks(node)
+		assert.Equal(t, node.predRanks, predRanks, "node %v", nodeId)
+	}
+}
+
+func TestPoaGraph_RepairOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	base := randomBases(r, 200)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g := PoaGraphConstruct()
+	g.AddBaseSequence(base, "base", true)
+	for i := 0; i < 30; i++ {
+		read := mutateSequence(r, base, 0.15)
+		// ragged ends that aren't aligned to the graph
+		if i%3 == 0 {
+			read = randomBases(r, 5) + read + randomBases(r, 5)
+		}
+		label := "read" + string(rune('a'+i))
+		g.AddSequenceAlignment(AlignStringToGraph(g, aln, read, label))
+		assertOrder(t, g)
+		seq, _ := g.Sequence(label)
+		assert.Equal(t, read, seq)
+		assert.Equal(t, len(read), len(g.pathIds(g.NbSequences()-1)))
+	}
+}
+
+func TestPoaGraph_reorderForEdge(t *testing.T) {
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("AC", "a", true)
+	g.AddBaseSequence("GT", "b", true)
+	assert.Equal(t, []int{0, 1, 2, 3}, g.nodeList)
+
+	// 3 -> 1 puts the path of b before node 1
+	g.AddEdge(3, 1, 1)
+	assert.True(t, g.reorderForEdge(g.nodes[3], g.nodes[1]))
+	assert.Equal(t, []int{0, 2, 3, 1}, g.nodeList)
+	for rank, nodeId := range g.nodeList {
+		assert.Equal(t, rank, g.nodes[nodeId].rank)
+	}
+	g.updateRanks()
+	g.needSort = false
+	assertOrder(t, g)
+
+	// 1 -> 2 closes the cycle 2 -> 3 -> 1 -> 2
+	g.AddEdge(1, 2, 1)
+	assert.False(t, g.reorderForEdge(g.nodes[1], g.nodes[2]))
+}
+
+func TestPoaGraph_TopoSortLongGraph(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	g := PoaGraphConstruct()
+	g.AddBaseSequence(randomBases(r, 100000), "long", true)
+	g.needSort = true
+	g.TopoSort()
+	assertOrder(t, g)
+}
+
+func benchmarkSortGraph(b *testing.B, incremental bool) {
+	r := rand.New(rand.NewSource(3))
+	base := randomBases(r, 2000)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g := PoaGraphConstruct()
+	g.AddBaseSequence(base, "base", true)
+	alignments := make([]*PairwiseAlignment, 0)
+	for i := 0; i < 5; i++ {
+		alignments = append(alignments, AlignStringToGraph(g, aln, mutateSequence(r, base, 0.1), "read"))
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		h := PoaGraphConstruct()
+		h.AddBaseSequence(base, "base", true)
+		b.StartTimer()
+		for _, pA := range alignments {
+			if !incremental {
+				h.needSort = true
+			}
+			h.AddSequenceAlignment(pA)
+		}
+	}
+}
+
+func BenchmarkPoaGraph_AddSequenceAlignmentRepairOrder(b *testing.B) {
+	benchmarkSortGraph(b, true)
+}
+
+func BenchmarkPoaGraph_AddSequenceAlignmentTopoSort(b *testing.B) {
+	benchmarkSortGraph(b, false)
+}
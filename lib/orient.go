@@ -0,0 +1,123 @@
+package PoaGo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// size of the k-mers used for the fast orientation check
+const orientationKmerSize int = 11
+
+// OrientationMode : how the strand of a read is decided before adding it to the graph
+type OrientationMode int
+
+const (
+	OrientNone  OrientationMode = iota // always use the read as given
+	OrientAlign                        // align both orientations and keep the better scoring one
+	OrientKmer                         // count k-mers shared with the graph sequences in both orientations
+)
+
+func ParseOrientationMode(mode string) (OrientationMode, error) {
+	switch strings.ToLower(mode) {
+	case "", "none":
+		return OrientNone, nil
+	case "align":
+		return OrientAlign, nil
+	case "kmer":
+		return OrientKmer, nil
+	default:
+		return OrientNone, errors.New(fmt.Sprintf("Unknown orientation mode %v, should be none, align or kmer", mode))
+	}
+}
+
+// IUPAC nucleotide complements, uracil is handled in ReverseComplement
+var iupacComplement = map[byte]byte{
+	'A': 'T', 'T': 'A', 'U': 'A', 'G': 'C', 'C': 'G',
+	'R': 'Y', 'Y': 'R', 'S': 'S', 'W': 'W', 'K': 'M', 'M': 'K',
+	'B': 'V', 'V': 'B', 'D': 'H', 'H': 'D', 'N': 'N',
+	'a': 't', 't': 'a', 'u': 'a', 'g': 'c', 'c': 'g',
+	'r': 'y', 'y': 'r', 's': 's', 'w': 'w', 'k': 'm', 'm': 'k',
+	'b': 'v', 'v': 'b', 'd': 'h', 'h': 'd', 'n': 'n',
+}
+
+// ReverseComplement returns the reverse complement of a DNA or RNA sequence using the IUPAC
+// ambiguity codes, if the sequence contains U and no T it is treated as RNA. Characters that
+// aren't nucleotide codes (gaps etc.) are kept as they are
+func ReverseComplement(sequence string) string {
+	rna := strings.ContainsAny(sequence, "Uu") && !strings.ContainsAny(sequence, "Tt")
+	n := len(sequence)
+	rc := make([]byte, n)
+	for i := 0; i < n; i++ {
+		c := sequence[n-1-i]
+		comp, ok := iupacComplement[c]
+		if !ok {
+			comp = c
+		}
+		if rna {
+			switch comp {
+			case 'T':
+				comp = 'U'
+			case 't':
+				comp = 'u'
+			}
+		}
+		rc[i] = comp
+	}
+	return string(rc)
+}
+
+// add the k-mers of sequences that have been added since the last call to the index
+func (self *PoaGraph) updateKmerIndex(k int) {
+	for ; self.nbIndexed < len(self.records); self.nbIndexed++ {
+		seq := strings.ToUpper(self.records[self.nbIndexed].Seq)
+		for i := 0; i+k <= len(seq); i++ {
+			self.kmerIndex[seq[i:i+k]] = true
+		}
+	}
+}
+
+func (self *PoaGraph) countSharedKmers(sequence string, k int) int {
+	seq := strings.ToUpper(sequence)
+	count := 0
+	for i := 0; i+k <= len(seq); i++ {
+		if self.kmerIndex[seq[i:i+k]] {
+			count += 1
+		}
+	}
+	return count
+}
+
+// returns true if the reverse complement of sequence shares more k-mers with the sequences in
+// the graph than the sequence itself
+func kmerOrientation(g *PoaGraph, sequence string, k int) bool {
+	g.updateKmerIndex(k)
+	forward := g.countSharedKmers(sequence, k)
+	reverse := g.countSharedKmers(ReverseComplement(sequence), k)
+	return reverse > forward
+}
+
+// AlignOrientedStringToGraph aligns sequence to the graph in the orientation picked by mode. If
+// the reverse complement is used the returned alignment is flagged, and AddSequenceAlignment
+// records the strand for the label
+func AlignOrientedStringToGraph(g *PoaGraph, aln *PairwiseAlignmentParameters, sequence, label string, mode OrientationMode) *PairwiseAlignment {
+	switch mode {
+	case OrientAlign:
+		forward := AlignStringToGraph(g, aln, sequence, label)
+		reverse := AlignStringToGraph(g, aln, ReverseComplement(sequence), label)
+		if reverse.score > forward.score {
+			reverse.reverse = true
+			return reverse
+		}
+		return forward
+	case OrientKmer:
+		if kmerOrientation(g, sequence, orientationKmerSize) {
+			pA := AlignStringToGraph(g, aln, ReverseComplement(sequence), label)
+			pA.reverse = true
+			return pA
+		}
+		return AlignStringToGraph(g, aln, sequence, label)
+	default:
+		return AlignStringToGraph(g, aln, sequence, label)
+	}
+}
@@ -0,0 +1,55 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReverseComplement(t *testing.T) {
+	assert.Equal(t, "ACGT", ReverseComplement("ACGT"))
+	assert.Equal(t, "NTTGCA", ReverseComplement("TGCAAN"))
+	assert.Equal(t, "acgt", ReverseComplement("acgt"))
+	// IUPAC ambiguity codes
+	assert.Equal(t, "BDHVKMRYSW", ReverseComplement("WSRYKMBDHV"))
+	// RNA keeps uracil
+	assert.Equal(t, "UUGCA", ReverseComplement("UGCAA"))
+	// gaps are kept
+	assert.Equal(t, "T-A", ReverseComplement("T-A"))
+}
+
+func TestParseOrientationMode(t *testing.T) {
+	m, ok := ParseOrientationMode("align")
+	assert.Nil(t, ok)
+	assert.Equal(t, OrientAlign, m)
+	m, ok = ParseOrientationMode("KMER")
+	assert.Nil(t, ok)
+	assert.Equal(t, OrientKmer, m)
+	_, ok = ParseOrientationMode("both")
+	assert.NotNil(t, ok)
+}
+
+func TestAlignOrientedStringToGraph(t *testing.T) {
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	base := "ACGGTCATTGACCTAGGCATTACGGATCCA"
+	read := ReverseComplement("ACGGTCATTGACTTAGGCATTACGGATCCA")
+
+	for _, mode := range []OrientationMode{OrientAlign, OrientKmer} {
+		g := PoaGraphConstruct()
+		_, _ = g.AddBaseSequence(base, "base", true)
+		pA := AlignOrientedStringToGraph(g, aln, read, "read", mode)
+		assert.True(t, pA.Reverse())
+		assert.True(t, pA.Aligned())
+		g.AddSequenceAlignment(pA)
+		assert.True(t, g.IsReversed("read"))
+		assert.False(t, g.IsReversed("base"))
+
+		seqNames, alignmentStrings := g.GenerateAlignmentStrings()
+		assert.Equal(t, "read_rc", seqNames[1])
+		assert.Equal(t, "ACGGTCATTGACTTAGGCATTACGGATCCA", alignmentStrings[1])
+	}
+
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence(base, "base", true)
+	pA := AlignOrientedStringToGraph(g, aln, base, "same", OrientAlign)
+	assert.False(t, pA.Reverse())
+}
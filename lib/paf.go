@@ -0,0 +1,97 @@
+package PoaGo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadMapping : a read placed on a target sequence by a mapper. Intervals are 0-based with the end
+// excluded as in PAF, the query interval is on the read as given even when it maps on the reverse strand
+type ReadMapping struct {
+	QueryName   string
+	QueryLen    int
+	QueryStart  int
+	QueryEnd    int
+	Reverse     bool
+	TargetName  string
+	TargetLen   int
+	TargetStart int
+	TargetEnd   int
+	Matches     int // number of matching bases
+	BlockLen    int // number of bases and gaps in the mapping
+	MapQ        int
+	Cigar       []CigarOp // alignment of the mapped part of the read to the target, nil if unknown
+}
+
+// ReadPAF reads the primary mappings of a PAF file, as ReadSAM does: lines with a mapping type tag
+// (tp:A:) other than P, like the secondary mappings of minimap2, are left out so each read is used once.
+// Of the other optional tags after the 12 mandatory columns only the CIGAR (cg:Z:) is kept, it goes
+// from the target start over the mapped part of the read, reverse complemented on the reverse strand
+func ReadPAF(r io.Reader) ([]ReadMapping, error) {
+	mappings := make([]ReadMapping, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	lineNb := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		lineNb += 1
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 12 {
+			return nil, &ParseError{Line: lineNb, Msg: fmt.Sprintf("expected 12 tab separated columns, got %d", len(fields))}
+		}
+		m := ReadMapping{QueryName: fields[0], TargetName: fields[5]}
+		switch fields[4] {
+		case "+":
+		case "-":
+			m.Reverse = true
+		default:
+			return nil, &ParseError{Line: lineNb, Msg: fmt.Sprintf("strand should be + or -, got %v", fields[4])}
+		}
+		numbers := []*int{&m.QueryLen, &m.QueryStart, &m.QueryEnd, nil, nil, &m.TargetLen, &m.TargetStart, &m.TargetEnd,
+			&m.Matches, &m.BlockLen, &m.MapQ}
+		for i, n := range numbers {
+			if n == nil {
+				continue
+			}
+			value, err := strconv.Atoi(fields[i+1])
+			if err != nil || value < 0 {
+				return nil, &ParseError{Line: lineNb, Msg: fmt.Sprintf("column %d should be a number >= 0, got %v", i+2, fields[i+1])}
+			}
+			*n = value
+		}
+		if m.QueryStart > m.QueryEnd || m.QueryEnd > m.QueryLen || m.TargetStart > m.TargetEnd || m.TargetEnd > m.TargetLen {
+			return nil, &ParseError{Line: lineNb, Msg: "mapping interval outside of the sequence"}
+		}
+		primary := true
+		for _, tag := range fields[12:] {
+			if strings.HasPrefix(tag, "tp:A:") {
+				primary = tag[5:] == "P"
+			}
+			if !strings.HasPrefix(tag, "cg:Z:") {
+				continue
+			}
+			cigar, err := ParseCigar(tag[5:])
+			if err != nil {
+				return nil, &ParseError{Line: lineNb, Msg: err.Error()}
+			}
+			queryLen, targetLen := cigarLengths(cigar)
+			if queryLen != m.QueryEnd-m.QueryStart || targetLen != m.TargetEnd-m.TargetStart {
+				return nil, &ParseError{Line: lineNb, Msg: "the CIGAR doesn't cover the mapping intervals"}
+			}
+			m.Cigar = cigar
+		}
+		if primary {
+			mappings = append(mappings, m)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
@@ -0,0 +1,40 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReadPAF(t *testing.T) {
+	input := "read1\t100\t2\t98\t+\tctg1\t1000\t10\t106\t90\t96\t60\ttp:A:P\tcg:Z:96M\n" +
+		"\n" +
+		"read2\t50\t0\t50\t-\tctg1\t1000\t500\t550\t48\t50\t12\r\n" +
+		// a secondary mapping of read1 would use it twice
+		"read1\t100\t2\t98\t+\tctg2\t1000\t10\t106\t90\t96\t0\ttp:A:S\tcg:Z:96M\n"
+	mappings, err := ReadPAF(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Equal(t, []ReadMapping{
+		{QueryName: "read1", QueryLen: 100, QueryStart: 2, QueryEnd: 98, TargetName: "ctg1", TargetLen: 1000,
+			TargetStart: 10, TargetEnd: 106, Matches: 90, BlockLen: 96, MapQ: 60, Cigar: []CigarOp{{'M', 96}}},
+		{QueryName: "read2", QueryLen: 50, QueryStart: 0, QueryEnd: 50, Reverse: true, TargetName: "ctg1", TargetLen: 1000,
+			TargetStart: 500, TargetEnd: 550, Matches: 48, BlockLen: 50, MapQ: 12},
+	}, mappings)
+}
+
+func TestReadPAF_Errors(t *testing.T) {
+	for _, input := range []string{
+		"read1\t100\t2\t98\t+\tctg1\t1000\t10\t106\t90\t96\n",
+		"read1\t100\t2\t98\t*\tctg1\t1000\t10\t106\t90\t96\t60\n",
+		"read1\t100\t2\tx\t+\tctg1\t1000\t10\t106\t90\t96\t60\n",
+		"read1\t100\t2\t98\t+\tctg1\t1000\t10\t1006\t90\t96\t60\n",
+		"read1\t100\t2\t98\t+\tctg1\t1000\t10\t106\t90\t96\t60\tcg:Z:96Q\n",
+		"read1\t100\t2\t98\t+\tctg1\t1000\t10\t106\t90\t96\t60\tcg:Z:90M2I4M\n",
+		"read1\t100\t2\t98\t+\tctg1\t1000\t10\t102\t90\t92\t60\tcg:Z:48M4S44M\n",
+	} {
+		_, err := ReadPAF(strings.NewReader("read0\t10\t0\t10\t+\tctg1\t1000\t0\t10\t10\t10\t60\n" + input))
+		if assert.NotNil(t, err, input) {
+			assert.Equal(t, 2, err.(*ParseError).Line)
+		}
+	}
+}
@@ -3,11 +3,12 @@ package PoaGo
 import (
 	"fmt"
 	"math"
-	"strings"
+	"sort"
 )
 
 // constants
-const maxFraction float64 = 0.5
+const DefaultMaxFraction float64 = 0.5
+const reverseSuffix string = "_rc"
 
 // Utils
 func intArrayReverse(arr []int) {
@@ -73,41 +74,42 @@ func arrEqual(arr1, arr2 []int) bool {
 	return true
 }
 
-// Edge : Directed edge object
+// Edge : Directed edge object, labels is the set of ids of the sequences going through it, see SeqRecord
 type Edge struct {
 	inNodeID  int
 	outNodeID int
-	labels    []string
-}
-
-// returns true if the string label is in the slice labels
-func checkForLabel(labels []string, label string) bool {
-	for _, s := range labels {
-		if s == label {
-			return true
-		}
-	}
-	return false
+	labels    labelSet
 }
 
 func (self Edge) String() string {
 	edgeString := fmt.Sprintf("(%v) -> (%v)", self.inNodeID, self.outNodeID)
-	if len(self.labels) == 0 {
+	if self.labels.count() == 0 {
 		return edgeString
 	}
-	return edgeString + fmt.Sprintf("%v", self.labels)
+	return edgeString + fmt.Sprintf("%v", self.labels.ids())
 }
 
 func EdgeConstruct(inNodeID, outNodeID int) Edge {
-	return Edge{inNodeID: inNodeID, outNodeID: outNodeID, labels: make([]string, 0)}
+	return Edge{inNodeID: inNodeID, outNodeID: outNodeID}
 }
 
-func (self *Edge) AddLabel(label string) {
-	// check if we already have this label
-	hasLabel := checkForLabel(self.labels, label)
-	if !hasLabel {
-		self.labels = append(self.labels, label)
-	}
+func (self *Edge) AddLabel(seqId int) {
+	self.labels.add(seqId)
+}
+
+// HasLabel returns true if the sequence goes through the edge
+func (self Edge) HasLabel(seqId int) bool {
+	return self.labels.has(seqId)
+}
+
+// SeqIds returns the ids of the sequences going through the edge in increasing order
+func (self Edge) SeqIds() []int {
+	return self.labels.ids()
+}
+
+// NbLabels returns the number of sequences going through the edge
+func (self Edge) NbLabels() int {
+	return self.labels.count()
 }
 
 // Node : Vertex in a DAG
@@ -117,6 +119,8 @@ type Node struct {
 	inEdges   map[int]*Edge // key: incident node, value: edge containing label(s)
 	outEdges  map[int]*Edge // key: incident node, value: edge containing label(s)
 	alignedTo []int
+	rank      int   // position in the topological order, valid while the graph doesn't need sorting
+	predRanks []int // ranks of the in-neighbors in increasing order, [-1] for a source
 }
 
 func NodeConstruct(id int, base string) *Node {
@@ -124,17 +128,17 @@ func NodeConstruct(id int, base string) *Node {
 		inEdges: make(map[int]*Edge), outEdges: make(map[int]*Edge)}
 }
 
-func (self *Node) addEdge(neighborID int, label string, edgeSet map[int]*Edge) {
+func (self *Node) addEdge(neighborID int, seqId int, edgeSet map[int]*Edge) {
 	// check if node already in adjacency map, if so, add the label. if not make the edge
 	// and init the label
 	_, check := edgeSet[neighborID]
 	if check {
 		edge := edgeSet[neighborID]
-		edge.AddLabel(label)
+		edge.AddLabel(seqId)
 		return
 	} else { // if not, make a new edge and label
 		edge := EdgeConstruct(neighborID, self.id)
-		edge.AddLabel(label)
+		edge.AddLabel(seqId)
 		edgeSet[neighborID] = &edge
 		return
 	}
@@ -144,12 +148,12 @@ func (self Node) String() string {
 	return fmt.Sprintf("(%v : %v)", self.id, self.base)
 }
 
-func (self *Node) AddInEdge(neighborID int, label string) {
-	self.addEdge(neighborID, label, self.inEdges)
+func (self *Node) AddInEdge(neighborID int, seqId int) {
+	self.addEdge(neighborID, seqId, self.inEdges)
 }
 
-func (self *Node) AddOutEdge(neighborID int, label string) {
-	self.addEdge(neighborID, label, self.outEdges)
+func (self *Node) AddOutEdge(neighborID int, seqId int) {
+	self.addEdge(neighborID, seqId, self.outEdges)
 }
 
 func (self Node) InDegree() int {
@@ -160,85 +164,71 @@ func (self Node) OutDegree() int {
 	return len(self.outEdges)
 }
 
-func (self Node) NextNode(label string) int {
-	nextId := -1
+// NextNode returns the node after this one on the path of the sequence, -1 at the end
+func (self Node) NextNode(seqId int) int {
 	for nid, edge := range self.outEdges {
-		hasLabel := checkForLabel(edge.labels, label)
-		if hasLabel {
-			nextId = nid
+		if edge.labels.has(seqId) {
+			return nid
 		}
 	}
-	return nextId
+	return -1
 }
 
-func collectEdgeLabels(edge Edge, labelSet *[]string) {
-	for _, label := range edge.labels {
-		contains := checkForLabel(*labelSet, label)
-		if !contains {
-			*labelSet = append(*labelSet, label)
-		}
+// the set of sequences going through the node
+func (self Node) labelSet() labelSet {
+	labels := make(labelSet, 0)
+	for _, edge := range self.inEdges {
+		labels.union(edge.labels)
+	}
+	for _, edge := range self.outEdges {
+		labels.union(edge.labels)
 	}
+	return labels
 }
 
-func (self Node) Labels() []string {
-	labelSet := make([]string, 0)
-
-	for i := range self.inEdges {
-		edge := self.inEdges[i]
-		collectEdgeLabels(*edge, &labelSet)
-	}
-	for i := range self.outEdges {
-		edge := self.outEdges[i]
-		collectEdgeLabels(*edge, &labelSet)
-	}
-	return labelSet
+// SeqIds returns the ids of the sequences going through the node in increasing order, see
+// PoaGraph.NodeLabels for their names
+func (self Node) SeqIds() []int {
+	return self.labelSet().ids()
 }
 
 // POAGraph : A partial order graph for multiple sequence alignment
 type PoaGraph struct {
-	nextNodeId int
-	nbNodes    int
-	nbEdges    int
-	nodeDict   map[int]*Node
-	nodeList   []int
-	needSort   bool
-	labels     []string
-	seqs       []string
-	starts     []int
+	nextNodeId  int
+	nbNodes     int
+	nbEdges     int
+	nodes       []*Node // indexed by node id
+	nodeList    []int   // node ids in topological order
+	needSort    bool
+	records     []*SeqRecord    // the sequences in the graph, indexed by their id
+	maxFraction float64         // used for the consensus rows in GenerateAlignmentStrings
+	kmerIndex   map[string]bool // k-mers of the added sequences, see kmerOrientation
+	nbIndexed   int             // number of sequences in kmerIndex
 }
 
 func PoaGraphConstruct() *PoaGraph {
 	return &PoaGraph{
-		nextNodeId: 0,
-		nbNodes:    0,
-		nbEdges:    0,
-		nodeDict:   make(map[int]*Node),
-		nodeList:   make([]int, 0),
-		needSort:   false,
-		labels:     make([]string, 0),
-		seqs:       make([]string, 0),
-		starts:     make([]int, 0)}
+		nextNodeId:  0,
+		nbNodes:     0,
+		nbEdges:     0,
+		nodes:       make([]*Node, 0),
+		nodeList:    make([]int, 0),
+		needSort:    false,
+		records:     make([]*SeqRecord, 0),
+		maxFraction: DefaultMaxFraction,
+		kmerIndex:   make(map[string]bool),
+		nbIndexed:   0}
 }
 
 func checkForNode(g *PoaGraph, nodeId int) bool {
-	_, check := g.nodeDict[nodeId]
-	return check
-}
-
-func isFinished(finished *[]int, query int) bool {
-	for _, x := range *finished {
-		if x == query {
-			return true
-		}
-	}
-	return false
+	return nodeId >= 0 && nodeId < len(g.nodes) && g.nodes[nodeId] != nil
 }
 
 func (self *PoaGraph) AddNode(base string) int {
 	// keep track of the idexing of the nodes
 	nodeId := self.nextNodeId
 	newNode := NodeConstruct(nodeId, base)
-	self.nodeDict[nodeId] = newNode
+	self.nodes = append(self.nodes, newNode)
 	self.nodeList = append(self.nodeList, nodeId)
 	self.nbNodes += 1
 	self.nextNodeId += 1
@@ -246,34 +236,67 @@ func (self *PoaGraph) AddNode(base string) int {
 	return nodeId
 }
 
-func (self *PoaGraph) AddEdge(startId, endId int, label string) {
+// AddEdge adds the sequence to the edge from startId to endId, making the edge if needed. Nothing is
+// done if one of the ids is negative, which stands for no node
+func (self *PoaGraph) AddEdge(startId, endId int, seqId int) error {
 	if startId < 0 || endId < 0 {
-		return
+		return nil
 	}
 
 	if !checkForNode(self, startId) {
-		fmt.Errorf("Start node %v not in graph", startId)
+		return fmt.Errorf("Start node %v not in graph", startId)
 	}
 	if !checkForNode(self, endId) {
-		fmt.Errorf("End node %v not in graph", startId)
+		return fmt.Errorf("End node %v not in graph", endId)
+	}
+	if seqId < 0 || seqId >= len(self.records) {
+		return fmt.Errorf("Sequence %v not in graph", seqId)
 	}
 
 	// keep track of the number of edges already going from start->end
-	oldNodeEdges := self.nodeDict[startId].OutDegree() + self.nodeDict[endId].InDegree()
+	oldNodeEdges := self.nodes[startId].OutDegree() + self.nodes[endId].InDegree()
 
-	self.nodeDict[startId].AddOutEdge(endId, label)
-	self.nodeDict[endId].AddInEdge(startId, label)
+	self.nodes[startId].AddOutEdge(endId, seqId)
+	self.nodes[endId].AddInEdge(startId, seqId)
 
-	newNodeEdges := self.nodeDict[startId].OutDegree() + self.nodeDict[endId].InDegree()
+	newNodeEdges := self.nodes[startId].OutDegree() + self.nodes[endId].InDegree()
 
 	if oldNodeEdges != newNodeEdges {
 		self.nbEdges += 1
 	}
 
 	self.needSort = true
+	return nil
 }
 
+// AddBaseSequence adds the sequence as a new path of unaligned nodes. With updateSequence it is a new
+// sequence of the graph, otherwise the nodes are part of the last sequence with this label, or of a
+// new sequence if there is none
 func (self *PoaGraph) AddBaseSequence(sequence string, label string, updateSequence bool) (int, int) {
+	if updateSequence {
+		return self.AddBaseRecord(SeqRecord{Name: label, Seq: sequence})
+	}
+	seqId := -1
+	for i := len(self.records) - 1; i >= 0 && seqId < 0; i-- {
+		if self.records[i].Name == label {
+			seqId = i
+		}
+	}
+	if seqId < 0 {
+		return self.AddBaseRecord(SeqRecord{Name: label, Seq: sequence})
+	}
+	return self.addBasePath(sequence, seqId)
+}
+
+// AddBaseRecord adds the sequence of rec as a new path of unaligned nodes, with its metadata
+func (self *PoaGraph) AddBaseRecord(rec SeqRecord) (int, int) {
+	record := self.addRecord(rec, false)
+	firstId, lastId := self.addBasePath(rec.Seq, record.Id)
+	record.start = firstId
+	return firstId, lastId
+}
+
+func (self *PoaGraph) addBasePath(sequence string, seqId int) (int, int) {
 	firstId, lastId := -1, -1
 	needSort := self.needSort
 	for _, base := range sequence {
@@ -282,49 +305,124 @@ func (self *PoaGraph) AddBaseSequence(sequence string, label string, updateSeque
 			firstId = nodeId
 		}
 		if lastId >= 0 {
-			self.AddEdge(lastId, nodeId, label)
+			self.AddEdge(lastId, nodeId, seqId)
 		}
 		lastId = nodeId
 	}
-	self.needSort = needSort
+	if !needSort && firstId >= 0 {
+		// the path was appended to the sorted nodes, they are still in order
+		self.needSort = false
+		self.updateRanksFrom(self.nodes[firstId].rank)
+	}
+	return firstId, lastId
+}
 
-	if updateSequence {
-		self.seqs = append(self.seqs, sequence)
-		self.labels = append(self.labels, label)
-		self.starts = append(self.starts, firstId)
+// sets the rank of each node from nodeList and the ranks of its predecessors, which the aligner uses
+func (self *PoaGraph) updateRanks() {
+	self.updateRanksFrom(0)
+}
+
+// same as updateRanks for the nodes from rank on, the ones before it must be up to date
+func (self *PoaGraph) updateRanksFrom(rank int) {
+	for r := rank; r < len(self.nodeList); r++ {
+		self.nodes[self.nodeList[r]].rank = r
+	}
+	for r := rank; r < len(self.nodeList); r++ {
+		self.updatePredRanks(self.nodes[self.nodeList[r]])
 	}
+}
 
-	return firstId, lastId
+func (self *PoaGraph) updatePredRanks(node *Node) {
+	node.predRanks = node.predRanks[:0]
+	for inNeighbor := range node.inEdges {
+		node.predRanks = append(node.predRanks, self.nodes[inNeighbor].rank)
+	}
+	if len(node.predRanks) == 0 {
+		node.predRanks = append(node.predRanks, -1)
+	}
+	sort.Ints(node.predRanks)
 }
 
-func dfs(g *PoaGraph, start int, marked map[int]bool, onStack map[int]bool, finished *[]int) {
-	marked[start] = true
-	onStack[start] = true
-	for neighbor, _ := range g.nodeDict[start].outEdges {
-		if !marked[neighbor] {
-			dfs(g, neighbor, marked, onStack, finished)
-		}
-		if onStack[neighbor] {
-			fmt.Println("has cycle")
-		}
+// sorts the graph if nodes or edges were added since the last sort, a cycle is a bug of whatever
+// built the graph
+func (self *PoaGraph) ensureSorted() {
+	if !self.needSort {
+		return
+	}
+	if err := self.TopoSort(); err != nil {
+		panic(err)
 	}
-	onStack[start] = false
-	*finished = append(*finished, start)
 }
 
-func (self *PoaGraph) TopoSort() {
-	marked := make(map[int]bool)
-	finished := make([]int, 0)
-	onStack := make(map[int]bool)
+// a node on the dfs stack and the out-neighbors still to visit
+type dfsFrame struct {
+	nodeId    int
+	neighbors []int
+}
+
+func (self *Node) outNeighbors() []int {
+	neighbors := make([]int, 0, len(self.outEdges))
+	for neighbor := range self.outEdges {
+		neighbors = append(neighbors, neighbor)
+	}
+	return neighbors
+}
+
+// TopoSort orders all the nodes by an iterative depth first search, nodes are finished after all the
+// nodes they lead to and the order is the reverse of the finishing order. Returns a *CycleError and
+// leaves the order as it was if the graph has a cycle
+func (self *PoaGraph) TopoSort() error {
+	order, cycle := self.dfsOrder()
+	if cycle != nil {
+		return cycle
+	}
+	self.nodeList = order
+	self.needSort = false
+	self.updateRanks()
+	return nil
+}
+
+// the topological order TopoSort uses, or the first cycle found
+func (self *PoaGraph) dfsOrder() ([]int, *CycleError) {
+	marked := make([]bool, len(self.nodes))
+	onStack := make([]bool, len(self.nodes))
+	finished := make([]int, 0, len(self.nodeList))
+	stack := make([]dfsFrame, 0)
 
 	for _, n := range self.nodeList {
-		if !isFinished(&finished, n) {
-			dfs(self, n, marked, onStack, &finished)
+		if marked[n] {
+			continue
+		}
+		marked[n], onStack[n] = true, true
+		stack = append(stack, dfsFrame{n, self.nodes[n].outNeighbors()})
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if len(top.neighbors) == 0 {
+				onStack[top.nodeId] = false
+				finished = append(finished, top.nodeId)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			neighbor := top.neighbors[0]
+			top.neighbors = top.neighbors[1:]
+			if onStack[neighbor] {
+				// the nodes on the stack from neighbor on lead back to it
+				cycle := []int{neighbor}
+				for i := len(stack) - 1; stack[i].nodeId != neighbor; i-- {
+					cycle = append(cycle, stack[i].nodeId)
+				}
+				cycle = append(cycle, neighbor)
+				intArrayReverse(cycle)
+				return nil, self.cycleError(cycle)
+			}
+			if !marked[neighbor] {
+				marked[neighbor], onStack[neighbor] = true, true
+				stack = append(stack, dfsFrame{neighbor, self.nodes[neighbor].outNeighbors()})
+			}
 		}
 	}
 	intArrayReverse(finished)
-	self.nodeList = finished
-	self.needSort = false
+	return finished, nil
 }
 
 func (self *PoaGraph) testSort() bool {
@@ -335,7 +433,7 @@ func (self *PoaGraph) testSort() bool {
 	seenNodes := make(map[int]bool)
 
 	for _, nodeIdx := range self.nodeList {
-		node := self.nodeDict[nodeIdx]
+		node := self.nodes[nodeIdx]
 		for inNeighbor, _ := range node.inEdges {
 			_, check := seenNodes[inNeighbor]
 			if !check {
@@ -347,20 +445,38 @@ func (self *PoaGraph) testSort() bool {
 	return true
 }
 
-func (self *PoaGraph) AddSequenceAlignment(pA *PairwiseAlignment) {
+// AddSequenceAlignment adds the aligned sequence to the graph, bases matched to a node with the same
+// base go through it and the others get new nodes. Returns an error and leaves the graph unchanged if
+// the alignment has no aligned bases or refers to nodes that aren't in the graph, and a *CycleError if
+// the new path closes a cycle, after taking the sequence out again
+func (self *PoaGraph) AddSequenceAlignment(pA *PairwiseAlignment) error {
 	validStringIdxs := make([]int, 0) // lookup how to resize
 	// add all of the not-None (not -1) string indices
 
 	strIdxs := pA.stringIdxs
 	sequence := pA.sequence
-	label := pA.label
 	matches := pA.matches
 
-	for _, si := range strIdxs {
+	for i, si := range strIdxs {
 		if si >= 0 {
 			validStringIdxs = append(validStringIdxs, si)
 		}
+		if matches[i] >= 0 && !checkForNode(self, matches[i]) {
+			return fmt.Errorf("AddSequenceAlignment: %v is aligned to node %v, not in the graph", pA.label, matches[i])
+		}
+	}
+	if len(validStringIdxs) == 0 {
+		return fmt.Errorf("AddSequenceAlignment: %v has no aligned bases", pA.label)
+	}
+
+	rec := SeqRecord{Name: pA.label}
+	if pA.record != nil {
+		rec = *pA.record
 	}
+	rec.Seq = sequence
+	record := self.addRecord(rec, pA.reverse)
+	seqId := record.Id
+	wasSorted, firstNewId := !self.needSort, self.nextNodeId
 
 	firstId, headId, tailId := -1, -1, -1
 
@@ -369,11 +485,21 @@ func (self *PoaGraph) AddSequenceAlignment(pA *PairwiseAlignment) {
 
 	// if the new aligned sequence has 'ragged ends' that aren't aligned to the graph, add them
 	if startSeqIdx > 0 {
-		firstId, headId = self.AddBaseSequence(sequence[:startSeqIdx], label, false)
+		firstId, headId = self.addBasePath(sequence[:startSeqIdx], seqId)
 	}
+	lastTailId := -1
 	if endSeqIdx < len(sequence) {
-		tailId, _ = self.AddBaseSequence(sequence[endSeqIdx+1:], label, false)
+		tailId, lastTailId = self.addBasePath(sequence[endSeqIdx+1:], seqId)
 	}
+	// the nodes of the new path, to take it out again if it closes a cycle. The ragged ends have
+	// consecutive ids
+	path := make([]int, 0, len(sequence))
+	for nodeId := firstId; firstId >= 0 && nodeId <= headId; nodeId++ {
+		path = append(path, nodeId)
+	}
+	// a path going through a node twice is a cycle on its own
+	onPath := make(map[int]int)
+	var cycle []int
 
 	//
 	for i, sIndex := range strIdxs {
@@ -389,46 +515,76 @@ func (self *PoaGraph) AddSequenceAlignment(pA *PairwiseAlignment) {
 		switch {
 		case matchId < 0:
 			nodeId = self.AddNode(base)
-		case self.nodeDict[matchId].base == base:
+		case self.nodes[matchId].base == base:
 			nodeId = matchId
 		default:
-			otherAligns := self.nodeDict[matchId].alignedTo
+			otherAligns := self.nodes[matchId].alignedTo
 			foundNode := -1
 			// check if this base is aligned to a node that is connected to a matching base
 			for _, otherNodeId := range otherAligns {
-				if self.nodeDict[otherNodeId].base == base {
+				if self.nodes[otherNodeId].base == base {
 					foundNode = otherNodeId
 				}
 			}
 			if foundNode < 0 {
 				nodeId = self.AddNode(base)
 				otherAligns = append(otherAligns, matchId)
-				self.nodeDict[nodeId].alignedTo = append(self.nodeDict[nodeId].alignedTo, otherAligns...)
-				for _, otherNodeId := range self.nodeDict[nodeId].alignedTo {
-					self.nodeDict[otherNodeId].alignedTo = append(self.nodeDict[otherNodeId].alignedTo, nodeId)
+				self.nodes[nodeId].alignedTo = append(self.nodes[nodeId].alignedTo, otherAligns...)
+				for _, otherNodeId := range self.nodes[nodeId].alignedTo {
+					self.nodes[otherNodeId].alignedTo = append(self.nodes[otherNodeId].alignedTo, nodeId)
 				}
 			} else {
 				nodeId = foundNode
 			}
 		}
-		self.AddEdge(headId, nodeId, label)
+		if at, ok := onPath[nodeId]; ok && cycle == nil {
+			cycle = append(append([]int(nil), path[at:]...), nodeId)
+		}
+		onPath[nodeId] = len(path)
+		self.AddEdge(headId, nodeId, seqId)
 		headId = nodeId
+		path = append(path, nodeId)
 		if firstId < 0 {
 			firstId = headId
 		}
 	}
-	self.AddEdge(headId, tailId, label)
+	self.AddEdge(headId, tailId, seqId)
+	for nodeId := tailId; tailId >= 0 && nodeId <= lastTailId; nodeId++ {
+		path = append(path, nodeId)
+	}
+	record.start = firstId
 
-	self.TopoSort()
+	// a sorted graph only needs the order repaired around the new path, otherwise sort it all
+	var err error
+	if cycle != nil {
+		err = self.cycleError(cycle)
+	} else if !wasSorted || !self.repairOrder(seqId, firstNewId) {
+		err = self.TopoSort()
+	}
+	if err != nil {
+		// the new path closes a cycle, without it the graph is as it was
+		self.removeSequence(seqId, path)
+		self.needSort = true
+		self.ensureSorted()
+	}
+	return err
+}
 
-	ok := self.testSort()
-	if !ok {
-		panic("AddSequenceAlignment: sort failed")
+// IsReversed returns true if the sequence with this label was added to the graph as its reverse
+// complement
+func (self *PoaGraph) IsReversed(label string) bool {
+	if rec, ok := self.Record(label); ok {
+		return rec.Reverse
 	}
+	return false
+}
 
-	self.seqs = append(self.seqs, sequence)
-	self.labels = append(self.labels, label)
-	self.starts = append(self.starts, firstId)
+// the name used for a sequence in the output, reverse complemented sequences get a suffix
+func (self *PoaGraph) displayName(i int) string {
+	if self.records[i].Reverse {
+		return self.records[i].Name + reverseSuffix
+	}
+	return self.records[i].Name
 }
 
 func makeAlignmentColumnArray(nbCols int) []string {
@@ -439,17 +595,18 @@ func makeAlignmentColumnArray(nbCols int) []string {
 	return charList
 }
 
-func (self *PoaGraph) GenerateAlignmentStrings() ([]string, []string) {
+// assigns each node to a column of the alignment, nodes that are aligned to each other share a
+// column. Returns the column of each node id and the number of columns
+func (self *PoaGraph) columnIndex() (map[int]int, int) {
 	columnIndex := make(map[int]int)
 	currentColumn := 0
 
 	for _, nodeIdx := range self.nodeList {
-		node := self.nodeDict[nodeIdx]
+		node := self.nodes[nodeIdx]
 		otherColumns := make([]int, 0)
 		for _, other := range node.alignedTo {
-			_, contains := columnIndex[other] // todo can be cleaned into one line?
-			if contains {
-				otherColumns = append(otherColumns, columnIndex[other])
+			if col, contains := columnIndex[other]; contains {
+				otherColumns = append(otherColumns, col)
 			}
 		}
 
@@ -463,50 +620,29 @@ func (self *PoaGraph) GenerateAlignmentStrings() ([]string, []string) {
 		columnIndex[node.id] = foundIdx
 	}
 
-	nColumns := currentColumn
-
-	seqNames := make([]string, 0) // todo should know the length of these slices before hand
-	alignmentStrings := make([]string, 0)
-
-	for i, start := range self.starts {
-		thisLabel := self.labels[i]
-		seqNames = append(seqNames, thisLabel)
-		curNodeId := start
-		charList := makeAlignmentColumnArray(nColumns)
-
-		for curNodeId >= 0 {
-			node := self.nodeDict[curNodeId]
-			charList[columnIndex[curNodeId]] = node.base
-			curNodeId = node.NextNode(thisLabel)
-		}
-
-		alnString := strings.Join(charList, "")
-		alignmentStrings = append(alignmentStrings, alnString)
-	}
-
-	consensusPaths, consensusBases, nbConsensus := self.AllConsensuses(maxFraction)
-	for i := 0; i < nbConsensus; i++ {
-		path := *consensusPaths[i]
-		bases := *consensusBases[i]
-
-		if len(path) != len(bases) {
-			e := fmt.Sprintf("Consensus %v doesn't have correct length", i)
-			panic(e)
-		}
+	return columnIndex, currentColumn
+}
 
-		charList := makeAlignmentColumnArray(nColumns)
-		for col := 0; col < len(path); col++ {
-			charList[columnIndex[path[col]]] = bases[col]
-		}
+// the columns of the i-th sequence in the alignment, gaps are "-"
+func (self *PoaGraph) alignmentRow(i int, columnIndex map[int]int, nColumns int) []string {
+	curNodeId := self.records[i].start
+	charList := makeAlignmentColumnArray(nColumns)
 
-		alnString := strings.Join(charList, "")
-		alignmentStrings = append(alignmentStrings, alnString)
-		seqNames = append(seqNames, fmt.Sprintf("Consensus%v", i))
+	for curNodeId >= 0 {
+		node := self.nodes[curNodeId]
+		charList[columnIndex[curNodeId]] = node.base
+		curNodeId = node.NextNode(i)
 	}
+	return charList
+}
 
-	//fmt.Println("step 3", alignmentStrings)
+func (self *PoaGraph) GenerateAlignmentStrings() ([]string, []string) {
+	w := &stringsAlignmentWriter{}
+	opts := AlignmentOptionsDefault()
+	opts.MaxFraction = self.maxFraction
+	_ = self.WriteAlignment(w, opts) // collecting the strings can't fail
 
-	return seqNames, alignmentStrings
+	return w.names, w.rows
 }
 
 // returns true of tup1 is greater than tup2, if they are equal, returns false
@@ -518,25 +654,18 @@ func compareEdgeScores(tup1, tup2 []int) bool {
 		if tup1[i] == tup2[i] {
 			continue
 		}
-		if tup1[i] > tup2[i] {
-			return true
-		}
+		return tup1[i] > tup2[i]
 	}
 	return false
 }
 
-func (self *PoaGraph) consensus(exclusions []string) ([]int, []string, [][]string) {
-	excludeLabels := make([]string, 0)
-	if len(exclusions) > 0 {
-		excludeLabels = exclusions
-	}
+// heaviest path through the graph, ignoring the sequences in exclusions. Returns the node ids and
+// bases along the path and the sequences going through each node
+func (self *PoaGraph) consensus(exclusions labelSet) ([]int, []string, []labelSet) {
 
-	if self.needSort {
-		self.TopoSort()
-		ok := self.testSort()
-		if !ok {
-			panic("Consensus, sort failed")
-		}
+	self.ensureSorted()
+	if len(self.nodeList) == 0 {
+		return []int{}, []string{}, []labelSet{}
 	}
 
 	nodesInReverse := make([]int, len(self.nodeList))
@@ -549,20 +678,17 @@ func (self *PoaGraph) consensus(exclusions []string) ([]int, []string, [][]strin
 		nextInPath[i] = -1
 	}
 	scores := make([]int, maxNodeId)
+	for i := range scores {
+		// ids of removed nodes can't start the path
+		scores[i] = math.MinInt
+	}
 
 	for _, nodeId := range nodesInReverse {
 		bestWeightScoreEdge := []int{-1, -1, -1}
 
-		for neighborId, edge := range self.nodeDict[nodeId].outEdges {
-			weight := 0
-
-			// go over the labels, the weight is the total count of labels that aren't in the 'exclude' list
-			for _, label := range edge.labels {
-				exclude := checkForLabel(excludeLabels, label)
-				if !exclude {
-					weight += 1
-				}
-			}
+		for neighborId, edge := range self.nodes[nodeId].outEdges {
+			// the weight is the number of sequences on the edge that aren't excluded
+			weight := edge.labels.countExcluding(exclusions)
 
 			weightScoreEdge := []int{weight, scores[neighborId], neighborId}
 			if compareEdgeScores(weightScoreEdge, bestWeightScoreEdge) {
@@ -579,12 +705,12 @@ func (self *PoaGraph) consensus(exclusions []string) ([]int, []string, [][]strin
 	pos := intArrayArgmax(scores)
 	path := make([]int, 0)
 	bases := make([]string, 0)
-	labels := make([][]string, 0)
+	labels := make([]labelSet, 0)
 
 	for pos >= 0 {
 		path = append(path, pos)
-		bases = append(bases, self.nodeDict[pos].base)
-		labels = append(labels, self.nodeDict[pos].Labels())
+		bases = append(bases, self.nodes[pos].base)
+		labels = append(labels, self.nodes[pos].labelSet())
 		pos = nextInPath[pos]
 	}
 
@@ -599,39 +725,13 @@ func (self *PoaGraph) AllConsensuses(maxFraction float64) ([]*[]int, []*[]string
 	// containers for accumulating
 	allPaths := make([]*[]int, 0)
 	allBases := make([]*[]string, 0)
-	exclusions := make([]string, 0)
 	nbConsensus := 0
 
-	for len(exclusions) < len(self.labels) {
-		path, bases, labelLists := self.consensus(exclusions)
-		if len(path) > 0 {
-			allPaths = append(allPaths, &path)
-			allBases = append(allBases, &bases)
-			nbConsensus += 1
-			labelCounts := make(map[string]int)
-			// tally up all of the labels we've seen in this consensus
-			for _, labelList := range labelLists {
-				for _, label := range labelList {
-					labelCounts[label] += 1
-				}
-			}
-
-			if len(self.seqs) != len(self.labels) {
-				panic("number of sequences != number of labels")
-			}
-
-			for i := 0; i < len(self.labels); i++ {
-				label := self.labels[i]
-				seq := self.seqs[i]
-				_, contains := labelCounts[label]
-				if contains {
-					count := float64(labelCounts[label])
-					if count >= maxFraction*float64(len(seq)) {
-						exclusions = append(exclusions, label)
-					}
-				}
-			}
-		}
+	for _, cluster := range self.consensusRounds(maxFraction) {
+		path, bases := cluster.Path, cluster.Bases
+		allPaths = append(allPaths, &path)
+		allBases = append(allBases, &bases)
+		nbConsensus += 1
 	}
 
 	return allPaths, allBases, nbConsensus
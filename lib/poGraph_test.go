@@ -32,7 +32,7 @@ func TestNode_String(t *testing.T) {
 
 func TestNode_AddInEdge(t *testing.T) {
 	n := NodeConstruct(0, "A")
-	n.AddInEdge(2, "seq")
+	n.AddInEdge(2, 0)
 	inDeg := n.InDegree()
 	if inDeg != 1 {
 		t.Errorf("Indegree error got %v should be 1", inDeg)
@@ -41,7 +41,7 @@ func TestNode_AddInEdge(t *testing.T) {
 
 func TestNode_AddOutEdge(t *testing.T) {
 	n := NodeConstruct(0, "A")
-	n.AddOutEdge(2, "seq")
+	n.AddOutEdge(2, 0)
 	inDeg := n.OutDegree()
 	if inDeg != 1 {
 		t.Errorf("Indegree error got %v should be 1", inDeg)
@@ -56,15 +56,15 @@ func TestEdgeConstruct(t *testing.T) {
 	if e.outNodeID != 1 {
 		t.Errorf("Out node id error, got %v, should be 1", e.outNodeID)
 	}
-	if len(e.labels) != 0 {
+	if e.NbLabels() != 0 {
 		t.Error("Initialized edge with non-empty set")
 	}
 }
 
 func TestEdge_AddLabel(t *testing.T) {
 	e := EdgeConstruct(0, 1)
-	e.AddLabel("seq")
-	if !checkForLabel(e.labels, "seq") {
+	e.AddLabel(3)
+	if !e.HasLabel(3) {
 		t.Error("AddLabel error, did not contain addition")
 	}
 }
@@ -97,9 +97,11 @@ func TestPoaGraph_AddNode(t *testing.T) {
 
 func TestPoaGraph_AddEdge(t *testing.T) {
 	g := PoaGraphConstruct()
+	g.addRecord(SeqRecord{Name: "s0"}, false)
+	g.addRecord(SeqRecord{Name: "s1"}, false)
 	nid1 := g.AddNode("A")
 	nid2 := g.AddNode("C")
-	g.AddEdge(nid1, nid2, "seq")
+	g.AddEdge(nid1, nid2, 0)
 
 	if g.nbEdges != 1 {
 		t.Error("number of edges error")
@@ -107,22 +109,22 @@ func TestPoaGraph_AddEdge(t *testing.T) {
 	if g.needSort != true {
 		t.Error("Needs sort error")
 	}
-	if len(g.nodeDict[nid1].outEdges) != 1 {
-		t.Errorf("node 0 out edge error, shold be length 1, got %v", len(g.nodeDict[nid1].inEdges))
+	if len(g.nodes[nid1].outEdges) != 1 {
+		t.Errorf("node 0 out edge error, shold be length 1, got %v", len(g.nodes[nid1].inEdges))
 	}
-	if len(g.nodeDict[nid1].inEdges) != 0 {
-		t.Errorf("node 0 in edge error, shold be length 0, got %v", len(g.nodeDict[nid1].inEdges))
+	if len(g.nodes[nid1].inEdges) != 0 {
+		t.Errorf("node 0 in edge error, shold be length 0, got %v", len(g.nodes[nid1].inEdges))
 	}
 
-	if len(g.nodeDict[nid2].outEdges) != 0 {
-		t.Errorf("node 1 out edge error, shold be length 0, got %v", len(g.nodeDict[nid1].inEdges))
+	if len(g.nodes[nid2].outEdges) != 0 {
+		t.Errorf("node 1 out edge error, shold be length 0, got %v", len(g.nodes[nid1].inEdges))
 	}
-	if len(g.nodeDict[nid2].inEdges) != 1 {
-		t.Errorf("node 1 in edge error, shold be length 1, got %v", len(g.nodeDict[nid1].inEdges))
+	if len(g.nodes[nid2].inEdges) != 1 {
+		t.Errorf("node 1 in edge error, shold be length 1, got %v", len(g.nodes[nid1].inEdges))
 	}
-	g.AddEdge(nid1, nid2, "seq2")
+	g.AddEdge(nid1, nid2, 1)
 
-	assert.True(t, len(g.nodeDict[nid1].outEdges[1].labels) == 2, "Didn't add edge label")
+	assert.True(t, g.nodes[nid1].outEdges[1].NbLabels() == 2, "Didn't add edge label")
 }
 
 func TestPoaGraph_AddBaseSequence(t *testing.T) {
@@ -136,6 +138,16 @@ func TestPoaGraph_AddBaseSequence(t *testing.T) {
 	if l != 6 {
 		t.Errorf("incorrect last id, should be 6, got %v", l)
 	}
+
+	// without updateSequence the nodes go to the sequence with the label, or a new one
+	f, l = g.AddBaseSequence("TT", label, false)
+	assert.Equal(t, []int{7, 8}, []int{f, l})
+	assert.Equal(t, 1, g.NbSequences())
+	f, l = g.AddBaseSequence("GG", "other", false)
+	assert.Equal(t, []int{9, 10}, []int{f, l})
+	assert.Equal(t, 2, g.NbSequences())
+	other, _ := g.Sequence("other")
+	assert.Equal(t, "GG", other)
 }
 
 func TestPoaGraph_TopoSort(t *testing.T) {
@@ -155,8 +167,8 @@ func TestPoaGraph_TopoSort(t *testing.T) {
 	//        N
 	//        7
 	next := g.AddNode("N")
-	g.AddEdge(2, next, "addition")
-	g.AddEdge(next, 4, "addition")
+	g.AddEdge(2, next, 0)
+	g.AddEdge(next, 4, 0)
 	g.TopoSort()
 	c = g.testSort()
 	if !c {
@@ -357,6 +369,42 @@ func TestAlignStringToGraph2(t *testing.T) {
 
 }
 
+func TestAlignStringToGraph_Modes(t *testing.T) {
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGT", "base", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+
+	// local leaves the unmatched bases out
+	pA := AlignStringToGraph(g, aln, "TTACGT", "new")
+	assert.Equal(t, []int{2, 3, 4, 5}, pA.stringIdxs)
+	assert.Equal(t, 16.0, pA.Score())
+
+	aln.SetMode(AlignSemiGlobal)
+	pA = AlignStringToGraph(g, aln, "TTACGT", "new")
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, pA.stringIdxs)
+	assert.Equal(t, []int{-1, -1, 0, 1, 2, 3}, pA.matches)
+	assert.Equal(t, 10.0, pA.Score())
+	// the graph ends are free
+	pA = AlignStringToGraph(g, aln, "CG", "new")
+	assert.Equal(t, []int{0, 1}, pA.stringIdxs)
+	assert.Equal(t, []int{1, 2}, pA.matches)
+
+	aln.SetMode(AlignGlobal)
+	pA = AlignStringToGraph(g, aln, "CG", "new")
+	assert.Equal(t, []int{-1, 0, 1, -1}, pA.stringIdxs)
+	assert.Equal(t, []int{0, 1, 2, 3}, pA.matches)
+	assert.Equal(t, 0.0, pA.Score())
+
+	g.AddSequenceAlignment(pA)
+	_, alignmentStrings := g.GenerateAlignmentStrings()
+	assert.Equal(t, "-CG-", alignmentStrings[1])
+
+	_, err := ParseAlignmentMode("semiglobal")
+	assert.Nil(t, err)
+	_, err = ParseAlignmentMode("glocal")
+	assert.NotNil(t, err)
+}
+
 func TestFqReader_Iter(t *testing.T) {
 	fH, ok := os.Open("../examples/example1.fa")
 	assert.True(t, ok == nil, "Error opening file")
@@ -386,4 +434,49 @@ func TestRandomSequence(t *testing.T) {
 	fmt.Println(reference)
 	fmt.Println(e_ref)
 
-}
\ No newline at end of file
+}
+func TestPoaGraph_Ranks(t *testing.T) {
+	g := writerTestGraph()
+	assert.False(t, g.needSort)
+	assert.Equal(t, len(g.nodeList), len(g.nodes))
+	for rank, nodeId := range g.nodeList {
+		node := g.nodes[nodeId]
+		assert.Equal(t, rank, node.rank)
+		if node.InDegree() == 0 {
+			assert.Equal(t, []int{-1}, node.predRanks)
+			continue
+		}
+		assert.Equal(t, node.InDegree(), len(node.predRanks))
+		for i, predRank := range node.predRanks {
+			assert.True(t, predRank < rank)
+			assert.True(t, i == 0 || node.predRanks[i-1] < predRank)
+			_, isPred := node.inEdges[g.nodeList[predRank]]
+			assert.True(t, isPred)
+		}
+	}
+
+	// a base sequence added to a sorted graph keeps it sorted, with ranks for the new nodes
+	first, last := g.AddBaseSequence("GG", "other", true)
+	assert.False(t, g.needSort)
+	assert.Equal(t, len(g.nodeList)-2, g.nodes[first].rank)
+	assert.Equal(t, []int{g.nodes[first].rank}, g.nodes[last].predRanks)
+	assert.False(t, checkForNode(g, len(g.nodes)))
+	assert.False(t, checkForNode(g, -1))
+}
+
+func BenchmarkAlignStringToGraph(b *testing.B) {
+	g := benchmarkGraph(100)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		AlignStringToGraph(g, aln, "GATTACAGGCATTCCAGATTACAGGCATTCCA", "read")
+	}
+}
+
+func TestCompareEdgeScores(t *testing.T) {
+	assert.True(t, compareEdgeScores([]int{2, 0, 1}, []int{1, 5, 2}))
+	// a heavier path after a lighter edge doesn't win
+	assert.False(t, compareEdgeScores([]int{1, 5, 2}, []int{2, 0, 1}))
+	assert.True(t, compareEdgeScores([]int{2, 5, 1}, []int{2, 0, 2}))
+	assert.False(t, compareEdgeScores([]int{2, 5, 1}, []int{2, 5, 2}))
+}
@@ -0,0 +1,112 @@
+package PoaGo
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PolishOptions struct {
+	MaxRounds int // stop after this many rounds if the consensus hasn't converged
+}
+
+func PolishOptionsDefault() PolishOptions {
+	return PolishOptions{MaxRounds: 5}
+}
+
+// PolishRound : the consensus after a round and its edit distance to the one before
+type PolishRound struct {
+	Consensus    string
+	EditDistance int
+	NbAligned    int // reads aligned to the consensus, the others are left out of the round
+}
+
+// PolishResult : the polished consensus, the rounds that led to it and the graph of the last round
+type PolishResult struct {
+	Consensus string
+	Rounds    []PolishRound
+	Converged bool // the last round didn't change the consensus
+	Cycling   bool // the last round gave back the consensus of an earlier round
+	Graph     *PoaGraph
+}
+
+// name of the consensus seeding the graph of a polishing round
+const polishSeedName = "polish_seed"
+
+// EditDistance returns the Levenshtein distance between two sequences
+func EditDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = intArrayMin([]int{prev[j] + 1, cur[j-1] + 1, prev[j-1] + cost})
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Polish improves the first consensus of the graph, which depends on the order the reads were added
+// in: each round builds a new graph from the consensus, realigns all the sequences of the graph to it
+// and takes the consensus of the reads, without the seed. Stops when a round doesn't change the
+// consensus, when it goes back to the consensus of an earlier round or after MaxRounds
+func (self *PoaGraph) Polish(aln *PairwiseAlignmentParameters, opts PolishOptions) (*PolishResult, error) {
+	_, bases, _ := self.consensus(nil)
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("Polish: the graph has no consensus")
+	}
+	return PolishSequences(strings.Join(bases, ""), self.records, aln, opts, self.maxFraction)
+}
+
+// PolishSequences polishes the consensus seed with the sequences of the records, see Polish
+func PolishSequences(seed string, records []*SeqRecord, aln *PairwiseAlignmentParameters, opts PolishOptions,
+	maxFraction float64) (*PolishResult, error) {
+	result := &PolishResult{Consensus: seed, Rounds: make([]PolishRound, 0)}
+	seen := map[string]bool{seed: true}
+	for round := 0; round < opts.MaxRounds; round++ {
+		g := PoaGraphConstruct()
+		g.SetMaxFraction(maxFraction)
+		g.AddBaseRecord(SeqRecord{Name: polishSeedName, Seq: result.Consensus})
+		nbAligned := 0
+		for _, rec := range records {
+			pA := AlignStringToGraph(g, aln, rec.Seq, rec.Name)
+			if !pA.Aligned() {
+				continue
+			}
+			pA.SetRecord(*rec)
+			if err := g.AddSequenceAlignment(pA); err != nil {
+				return nil, err
+			}
+			nbAligned += 1
+		}
+		if nbAligned == 0 {
+			return nil, fmt.Errorf("Polish: no sequence aligns to the consensus")
+		}
+		// the seed is the first sequence with its name
+		if err := g.RemoveSequence(polishSeedName); err != nil {
+			return nil, err
+		}
+		_, bases, _ := g.consensus(nil)
+		consensus := strings.Join(bases, "")
+		distance := EditDistance(result.Consensus, consensus)
+		result.Rounds = append(result.Rounds, PolishRound{Consensus: consensus, EditDistance: distance, NbAligned: nbAligned})
+		result.Consensus, result.Graph = consensus, g
+		if distance == 0 {
+			result.Converged = true
+			break
+		}
+		if seen[consensus] {
+			result.Cycling = true
+			break
+		}
+		seen[consensus] = true
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, EditDistance("", ""))
+	assert.Equal(t, 3, EditDistance("ACG", ""))
+	assert.Equal(t, 3, EditDistance("", "ACG"))
+	assert.Equal(t, 0, EditDistance("ACGT", "ACGT"))
+	assert.Equal(t, 1, EditDistance("ACGT", "AGGT"))
+	assert.Equal(t, 1, EditDistance("ACGT", "ACT"))
+	assert.Equal(t, 1, EditDistance("ACGT", "ACGGT"))
+	assert.Equal(t, 3, EditDistance("kitten", "sitting"))
+}
+
+func TestPoaGraph_Polish(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	truth := randomBases(r, 150)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g := PoaGraphConstruct()
+	g.AddBaseSequence(mutateSequence(r, truth, 0.1), "read0", true)
+	for i := 1; i < 15; i++ {
+		label := "read" + string(rune('a'+i))
+		g.AddSequenceAlignment(AlignStringToGraph(g, aln, mutateSequence(r, truth, 0.1), label))
+	}
+	_, bases, _ := g.consensus(nil)
+	before := EditDistance(truth, strings.Join(bases, ""))
+
+	result, err := g.Polish(aln, PolishOptionsDefault())
+	assert.Nil(t, err)
+	assert.True(t, len(result.Rounds) > 0)
+	assert.True(t, len(result.Rounds) <= PolishOptionsDefault().MaxRounds)
+	last := result.Rounds[len(result.Rounds)-1]
+	assert.Equal(t, result.Consensus, last.Consensus)
+	assert.Equal(t, result.Converged, last.EditDistance == 0)
+	assert.True(t, result.Converged || result.Cycling || len(result.Rounds) == PolishOptionsDefault().MaxRounds)
+	assert.True(t, EditDistance(truth, result.Consensus) <= before)
+
+	// the graph of the last round has the reads only
+	assert.Equal(t, g.NbSequences(), result.Graph.NbSequences())
+	assert.Equal(t, 15, last.NbAligned)
+	_, ok := result.Graph.Record(polishSeedName)
+	assert.False(t, ok)
+	assert.Nil(t, result.Graph.Validate())
+	for _, rec := range g.Records() {
+		seq, _ := result.Graph.Sequence(rec.Name)
+		assert.Equal(t, rec.Seq, seq)
+	}
+}
+
+func TestPolishSequences_Converged(t *testing.T) {
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	records := []*SeqRecord{{Name: "a", Seq: "ACGTACGTTT"}, {Name: "b", Seq: "ACGTACGTTT"}, {Name: "c", Seq: "ACGTAGGTTT"}}
+	// the seed has a base that none of the reads have
+	result, err := PolishSequences("ACGTCCGTTT", records, aln, PolishOptionsDefault(), DefaultMaxFraction)
+	assert.Nil(t, err)
+	assert.True(t, result.Converged)
+	assert.Equal(t, "ACGTACGTTT", result.Consensus)
+	assert.Equal(t, 2, len(result.Rounds))
+	assert.Equal(t, 1, result.Rounds[0].EditDistance)
+	assert.Equal(t, 0, result.Rounds[1].EditDistance)
+
+	opts := PolishOptionsDefault()
+	opts.MaxRounds = 1
+	result, _ = PolishSequences("ACGTCCGTTT", records, aln, opts, DefaultMaxFraction)
+	assert.False(t, result.Converged)
+	assert.Equal(t, 1, len(result.Rounds))
+}
@@ -0,0 +1,491 @@
+package PoaGo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const dnaAlphabet string = "ACGT"
+const aminoAlphabet string = "ACDEFGHIKLMNPQRSTVWY"
+
+// residues an IUPAC nucleotide code can stand for
+var iupacBases = map[string]string{
+	"A": "A", "C": "C", "G": "G", "T": "T", "U": "T",
+	"R": "AG", "Y": "CT", "S": "CG", "W": "AT", "K": "GT", "M": "AC",
+	"B": "CGT", "D": "AGT", "H": "ACT", "V": "ACG", "N": "ACGT",
+}
+
+// ambiguous amino acid codes
+var aminoAmbiguity = map[string]string{"B": "DN", "Z": "EQ", "J": "IL", "X": aminoAlphabet}
+
+// background amino acid frequencies (BLOSUM62), in aminoAlphabet order
+var aminoBackground = []float64{
+	0.0787945, 0.0151600, 0.0535222, 0.0668298, 0.0397062, 0.0695071, 0.0229198,
+	0.0590092, 0.0594422, 0.0963728, 0.0237718, 0.0414386, 0.0482904, 0.0395639,
+	0.0540978, 0.0683364, 0.0540687, 0.0673417, 0.0114135, 0.0304133,
+}
+
+// SequenceWeighting : how the sequences of the alignment are weighted when counting residues
+type SequenceWeighting int
+
+const (
+	WeightNone     SequenceWeighting = iota // every sequence counts once
+	WeightHenikoff                          // Henikoff & Henikoff 1994 position based weights
+)
+
+func ParseSequenceWeighting(weighting string) (SequenceWeighting, error) {
+	switch strings.ToLower(weighting) {
+	case "", "none":
+		return WeightNone, nil
+	case "henikoff", "pb":
+		return WeightHenikoff, nil
+	default:
+		return WeightNone, errors.New(fmt.Sprintf("Unknown sequence weighting %v, should be none or henikoff", weighting))
+	}
+}
+
+type ProfileOptions struct {
+	Pseudocount float64 // added to the count of every residue in every column
+	Weighting   SequenceWeighting
+}
+
+// Profile : weighted residue counts for each column of the alignment
+type Profile struct {
+	Name     string
+	Alphabet string      // residues, in the order of the counts
+	IsDNA    bool        // nucleotide alphabet, otherwise amino acids
+	Counts   [][]float64 // weighted residue counts per column, without pseudocounts
+	Gaps     []float64   // weighted gap counts per column
+	Weights  []float64   // weight of each sequence, they sum to the number of sequences
+	opts     ProfileOptions
+	rows     [][]string // the alignment, used for the HMM state paths
+}
+
+// BuildProfile counts the residues in each column of the alignment GenerateAlignmentStrings makes,
+// consensus rows are not included
+func (self *PoaGraph) BuildProfile(name string, opts ProfileOptions) *Profile {
+	self.ensureSorted()
+	columnIndex, nColumns := self.columnIndex()
+
+	rows := make([][]string, len(self.records))
+	for i := range self.records {
+		rows[i] = self.alignmentRow(i, columnIndex, nColumns)
+	}
+
+	isDNA := true
+	for _, row := range rows {
+		for _, c := range row {
+			if _, ok := iupacBases[strings.ToUpper(c)]; !ok && c != "-" {
+				isDNA = false
+			}
+		}
+	}
+	alphabet := aminoAlphabet
+	if isDNA {
+		alphabet = dnaAlphabet
+	}
+
+	weights := make([]float64, len(rows))
+	switch opts.Weighting {
+	case WeightHenikoff:
+		weights = henikoffWeights(rows, nColumns)
+	default:
+		for i := range weights {
+			weights[i] = 1.0
+		}
+	}
+
+	counts := make([][]float64, nColumns)
+	gaps := make([]float64, nColumns)
+	for col := 0; col < nColumns; col++ {
+		counts[col] = make([]float64, len(alphabet))
+		for i, row := range rows {
+			if row[col] == "-" {
+				gaps[col] += weights[i]
+				continue
+			}
+			// ambiguous residues are spread over the residues they stand for
+			residues := residueSet(strings.ToUpper(row[col]), isDNA)
+			for _, r := range residues {
+				counts[col][strings.IndexRune(alphabet, r)] += weights[i] / float64(len(residues))
+			}
+		}
+	}
+
+	return &Profile{Name: name, Alphabet: alphabet, IsDNA: isDNA, Counts: counts, Gaps: gaps,
+		Weights: weights, opts: opts, rows: rows}
+}
+
+func residueSet(residue string, isDNA bool) string {
+	if isDNA {
+		return iupacBases[residue]
+	}
+	if strings.Contains(aminoAlphabet, residue) {
+		return residue
+	}
+	if set, ok := aminoAmbiguity[residue]; ok {
+		return set
+	}
+	return aminoAlphabet
+}
+
+// position based weights, each column gives 1/(r*s) to a sequence where r is the number of
+// different residues in the column and s the number of sequences sharing the residue
+func henikoffWeights(rows [][]string, nColumns int) []float64 {
+	weights := make([]float64, len(rows))
+	for col := 0; col < nColumns; col++ {
+		residueCounts := make(map[string]int)
+		for _, row := range rows {
+			if row[col] != "-" {
+				residueCounts[strings.ToUpper(row[col])] += 1
+			}
+		}
+		for i, row := range rows {
+			if row[col] != "-" {
+				weights[i] += 1.0 / float64(len(residueCounts)*residueCounts[strings.ToUpper(row[col])])
+			}
+		}
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	for i := range weights {
+		if total > 0 {
+			weights[i] *= float64(len(rows)) / total
+		} else {
+			weights[i] = 1.0
+		}
+	}
+	return weights
+}
+
+func (self *Profile) NbColumns() int {
+	return len(self.Counts)
+}
+
+// EffectiveNbSeqs is the sum of the sequence weights
+func (self *Profile) EffectiveNbSeqs() float64 {
+	total := 0.0
+	for _, w := range self.Weights {
+		total += w
+	}
+	return total
+}
+
+// PseudoCounts returns the residue counts of a column with the pseudocount added
+func (self *Profile) PseudoCounts(col int) []float64 {
+	counts := make([]float64, len(self.Alphabet))
+	for i, c := range self.Counts[col] {
+		counts[i] = c + self.opts.Pseudocount
+	}
+	return counts
+}
+
+// Frequencies returns the residue frequencies of a column including the pseudocounts
+func (self *Profile) Frequencies(col int) []float64 {
+	counts := self.PseudoCounts(col)
+	total := 0.0
+	for _, c := range counts {
+		total += c
+	}
+	freqs := make([]float64, len(counts))
+	for i, c := range counts {
+		if total > 0 {
+			freqs[i] = c / total
+		} else {
+			freqs[i] = 1.0 / float64(len(counts))
+		}
+	}
+	return freqs
+}
+
+// MatchColumns returns the columns with a weighted gap fraction below maxGapFraction
+func (self *Profile) MatchColumns(maxGapFraction float64) []int {
+	total := self.EffectiveNbSeqs()
+	columns := make([]int, 0)
+	for col := range self.Counts {
+		if total > 0 && self.Gaps[col]/total < maxGapFraction {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+func (self *Profile) background() []float64 {
+	if !self.IsDNA {
+		return aminoBackground
+	}
+	bg := make([]float64, len(self.Alphabet))
+	for i := range bg {
+		bg[i] = 1.0 / float64(len(bg))
+	}
+	return bg
+}
+
+// WritePfmTSV writes the position frequency matrix as a table, one line per column with the
+// residue counts (including pseudocounts) and the gap count
+func (self *Profile) WritePfmTSV(w io.Writer) error {
+	header := "position\t" + strings.Join(strings.Split(self.Alphabet, ""), "\t") + "\tgaps"
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+	for col := range self.Counts {
+		fields := []string{fmt.Sprintf("%d", col+1)}
+		for _, c := range self.PseudoCounts(col) {
+			fields = append(fields, formatCount(c))
+		}
+		fields = append(fields, formatCount(self.Gaps[col]))
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJaspar writes the position frequency matrix in JASPAR format, one row per residue
+func (self *Profile) WriteJaspar(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, ">%s\t%s\n", self.Name, self.Name); err != nil {
+		return err
+	}
+	for i, r := range self.Alphabet {
+		fields := make([]string, len(self.Counts))
+		for col := range self.Counts {
+			fields[col] = formatCount(self.PseudoCounts(col)[i])
+		}
+		if _, err := fmt.Fprintf(w, "%c  [ %s ]\n", r, strings.Join(fields, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMeme writes the frequencies as a MEME (version 4) motif file
+func (self *Profile) WriteMeme(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "MEME version 4\n\nALPHABET= %s\n\n", self.Alphabet)
+	if self.IsDNA {
+		b.WriteString("strands: + -\n\n")
+	}
+	b.WriteString("Background letter frequencies\n")
+	for i, f := range self.background() {
+		fmt.Fprintf(&b, "%c %.4f ", self.Alphabet[i], f)
+	}
+	fmt.Fprintf(&b, "\n\nMOTIF %s\nletter-probability matrix: alength= %d w= %d nsites= %d E= 0\n",
+		self.Name, len(self.Alphabet), len(self.Counts), int(math.Round(self.EffectiveNbSeqs())))
+	for col := range self.Counts {
+		for _, f := range self.Frequencies(col) {
+			fmt.Fprintf(&b, " %.6f", f)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatCount(c float64) string {
+	if c == math.Trunc(c) {
+		return fmt.Sprintf("%d", int(c))
+	}
+	return fmt.Sprintf("%.3f", c)
+}
+
+// HMM state transitions, in the order of the HMMER3 file format
+const (
+	tMM = iota
+	tMI
+	tMD
+	tIM
+	tII
+	tDM
+	tDD
+)
+
+// prior counts added to the observed transitions
+var transitionPriors = []float64{1.0, 0.05, 0.05, 0.5, 0.5, 0.5, 0.5}
+
+// WriteHmmer3 writes a profile HMM in HMMER3/f format. Columns with a weighted gap fraction below
+// maxGapFraction become match states, the residues in the other columns are insertions. Leading and
+// trailing gaps of a sequence are treated as local entry and exit so they don't count as deletions.
+// The model isn't calibrated (no STATS lines), run it through hmmbuild/hmmcalibrate if E-values
+// are needed
+func (self *Profile) WriteHmmer3(w io.Writer, maxGapFraction float64) error {
+	matchCols := self.MatchColumns(maxGapFraction)
+	M := len(matchCols)
+	if M == 0 {
+		return errors.New("WriteHmmer3: no column passes the gap fraction threshold")
+	}
+	// node of each column, columns between match k and k+1 belong to node k
+	isMatch := make([]bool, self.NbColumns())
+	nodeOf := make([]int, self.NbColumns())
+	k := 0
+	for col := 0; col < self.NbColumns(); col++ {
+		if k < M && matchCols[k] == col {
+			isMatch[col] = true
+			k += 1
+		}
+		nodeOf[col] = k
+	}
+
+	// transition counts for the nodes 0..M
+	trans := make([][]float64, M+1)
+	for k := range trans {
+		trans[k] = make([]float64, 7)
+	}
+	for i, row := range self.rows {
+		// first and last match state with a residue
+		first, last := -1, -1
+		for col, c := range row {
+			if isMatch[col] && c != "-" {
+				if first < 0 {
+					first = col
+				}
+				last = col
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		// local entry into the first match, it isn't counted as a transition
+		prev := ""
+		for col := first; col <= last; col++ {
+			var state string
+			switch {
+			case isMatch[col] && row[col] != "-":
+				state = "M"
+			case isMatch[col]:
+				state = "D"
+			case row[col] != "-":
+				state = "I"
+			default:
+				continue
+			}
+			from := nodeOf[col]
+			if isMatch[col] {
+				from -= 1
+			}
+			if t := transitionIndex(prev, state); t >= 0 {
+				trans[from][t] += self.Weights[i]
+			}
+			prev = state
+		}
+		// local exit after the last match isn't counted either
+	}
+
+	var b strings.Builder
+	alph := "amino"
+	if self.IsDNA {
+		alph = "DNA"
+	}
+	fmt.Fprintf(&b, "HMMER3/f [PoaGo]\nNAME  %s\nLENG  %d\nALPH  %s\n", self.Name, M, alph)
+	b.WriteString("RF    no\nMM    no\nCONS  yes\nCS    no\nMAP   yes\n")
+	fmt.Fprintf(&b, "NSEQ  %d\nEFFN  %f\nCKSUM 0\n", len(self.rows), self.EffectiveNbSeqs())
+	b.WriteString("HMM     ")
+	for _, r := range self.Alphabet {
+		fmt.Fprintf(&b, "     %c   ", r)
+	}
+	b.WriteString("\n            m->m     m->i     m->d     i->m     i->i     d->m     d->d\n")
+
+	bg := self.background()
+	compo := make([]float64, len(self.Alphabet))
+	for _, col := range matchCols {
+		for i, f := range self.Frequencies(col) {
+			compo[i] += f / float64(M)
+		}
+	}
+	b.WriteString("  COMPO  ")
+	writeLogProbs(&b, compo)
+	b.WriteString("\n         ")
+	writeLogProbs(&b, bg)
+	b.WriteString("\n         ")
+	writeLogProbs(&b, transitionProbs(trans[0], 0, M))
+	b.WriteString("\n")
+
+	for k := 1; k <= M; k++ {
+		col := matchCols[k-1]
+		freqs := self.Frequencies(col)
+		fmt.Fprintf(&b, " %7d ", k)
+		writeLogProbs(&b, freqs)
+		best := 0
+		for i, f := range freqs {
+			if f > freqs[best] {
+				best = i
+			}
+		}
+		cons := string(self.Alphabet[best])
+		if freqs[best] < 0.5 {
+			cons = strings.ToLower(cons)
+		}
+		fmt.Fprintf(&b, " %6d %s - - -\n         ", col+1, cons)
+		writeLogProbs(&b, bg)
+		b.WriteString("\n         ")
+		writeLogProbs(&b, transitionProbs(trans[k], k, M))
+		b.WriteString("\n")
+	}
+	b.WriteString("//\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func transitionIndex(from, to string) int {
+	switch from + to {
+	case "MM":
+		return tMM
+	case "MI":
+		return tMI
+	case "MD":
+		return tMD
+	case "IM":
+		return tIM
+	case "II":
+		return tII
+	case "DM":
+		return tDM
+	case "DD":
+		return tDD
+	}
+	// the entry into the model and insert <-> delete, which Plan7 doesn't have
+	return -1
+}
+
+// normalizes the transition counts of node k (of M) into probabilities, node 0 has no delete state
+// and the last node can't go to a delete state
+func transitionProbs(counts []float64, k, M int) []float64 {
+	c := make([]float64, 7)
+	for i := range c {
+		c[i] = counts[i] + transitionPriors[i]
+	}
+	if k == M {
+		c[tMD], c[tDM], c[tDD] = 0, 1, 0
+	}
+	if k == 0 {
+		c[tDM], c[tDD] = 1, 0
+	}
+	probs := make([]float64, 7)
+	for _, group := range [][]int{{tMM, tMI, tMD}, {tIM, tII}, {tDM, tDD}} {
+		total := 0.0
+		for _, t := range group {
+			total += c[t]
+		}
+		for _, t := range group {
+			probs[t] = c[t] / total
+		}
+	}
+	return probs
+}
+
+// HMMER files store probabilities as negative natural logs, "*" for zero
+func writeLogProbs(b *strings.Builder, probs []float64) {
+	for _, p := range probs {
+		if p <= 0 {
+			fmt.Fprintf(b, " %8s", "*")
+		} else {
+			fmt.Fprintf(b, " %8.5f", math.Abs(-math.Log(p)))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func profileTestGraph() *PoaGraph {
+	g := PoaGraphConstruct()
+	_, _ = g.AddBaseSequence("ACGT", "s1", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGT", "s2"))
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACT", "s3"))
+	return g
+}
+
+func TestPoaGraph_BuildProfile(t *testing.T) {
+	g := profileTestGraph()
+	p := g.BuildProfile("test", ProfileOptions{Pseudocount: 1.0})
+
+	assert.True(t, p.IsDNA)
+	assert.Equal(t, "ACGT", p.Alphabet)
+	assert.Equal(t, 4, p.NbColumns())
+	assert.Equal(t, []float64{3, 0, 0, 0}, p.Counts[0])
+	assert.Equal(t, []float64{0, 0, 2, 0}, p.Counts[2])
+	assert.Equal(t, 1.0, p.Gaps[2])
+	assert.Equal(t, []float64{4, 1, 1, 1}, p.PseudoCounts(0))
+	assert.InDelta(t, 4.0/7.0, p.Frequencies(0)[0], 1e-9)
+	assert.Equal(t, []int{0, 1, 3}, p.MatchColumns(0.3))
+
+	var buf bytes.Buffer
+	assert.Nil(t, p.WriteJaspar(&buf))
+	assert.Contains(t, buf.String(), "A  [ 4 1 1 1 ]")
+
+	buf.Reset()
+	assert.Nil(t, p.WriteMeme(&buf))
+	assert.Contains(t, buf.String(), "letter-probability matrix: alength= 4 w= 4 nsites= 3")
+
+	buf.Reset()
+	assert.Nil(t, p.WritePfmTSV(&buf))
+	assert.Equal(t, 5, len(strings.Split(strings.TrimSpace(buf.String()), "\n")))
+}
+
+func TestHenikoffWeights(t *testing.T) {
+	rows := [][]string{{"A", "A"}, {"A", "A"}, {"C", "C"}}
+	w := henikoffWeights(rows, 2)
+	// the odd sequence out gets twice the weight of the two identical ones
+	assert.InDelta(t, 0.75, w[0], 1e-9)
+	assert.InDelta(t, 0.75, w[1], 1e-9)
+	assert.InDelta(t, 1.5, w[2], 1e-9)
+}
+
+func TestProfile_WriteHmmer3(t *testing.T) {
+	g := profileTestGraph()
+	p := g.BuildProfile("test", ProfileOptions{Pseudocount: 0.5})
+
+	var buf bytes.Buffer
+	assert.Nil(t, p.WriteHmmer3(&buf, 0.3))
+	hmm := buf.String()
+	assert.True(t, strings.HasPrefix(hmm, "HMMER3/f"))
+	assert.Contains(t, hmm, "LENG  3\n")
+	assert.Contains(t, hmm, "ALPH  DNA\n")
+	assert.True(t, strings.HasSuffix(hmm, "//\n"))
+	// three nodes plus node 0 with 3 lines each, 1 of them is COMPO, plus the transition header
+	lines := strings.Split(hmm, "\n")
+	nodeLines := 0
+	for _, l := range lines {
+		if strings.HasPrefix(l, "       ") || strings.HasPrefix(l, "  COMPO") {
+			nodeLines += 1
+		}
+	}
+	assert.Equal(t, 4*3+1, nodeLines)
+
+	assert.NotNil(t, p.WriteHmmer3(&buf, 0.0))
+}
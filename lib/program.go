@@ -0,0 +1,57 @@
+package PoaGo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProgramInfo : the program that wrote an output, recorded in the SAM, VCF and GFA headers
+type ProgramInfo struct {
+	Name        string
+	Version     string
+	Revision    string
+	CommandLine string
+}
+
+// Program is set by the command line tool before writing any output
+var Program = ProgramInfo{Name: "PoaGo", Version: "NOTSET", Revision: "NOTSET"}
+
+// VersionString returns the version followed by the revision, when it is known
+func (self ProgramInfo) VersionString() string {
+	if self.Revision == "" || self.Revision == "NOTSET" {
+		return self.Version
+	}
+	return fmt.Sprintf("%s (%s)", self.Version, self.Revision)
+}
+
+// header values can't contain tabs or line breaks
+func headerValue(value string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(value)
+}
+
+// SAM @PG line
+func (self ProgramInfo) samHeader() string {
+	line := fmt.Sprintf("@PG\tID:%s\tPN:%s\tVN:%s", self.Name, self.Name, headerValue(self.VersionString()))
+	if self.CommandLine != "" {
+		line += "\tCL:" + headerValue(self.CommandLine)
+	}
+	return line + "\n"
+}
+
+// VCF ##source line, and ##commandline when known
+func (self ProgramInfo) vcfHeader() string {
+	lines := fmt.Sprintf("##source=%s %s\n", self.Name, headerValue(self.VersionString()))
+	if self.CommandLine != "" {
+		lines += fmt.Sprintf("##commandline=\"%s\"\n", strings.ReplaceAll(headerValue(self.CommandLine), "\"", "'"))
+	}
+	return lines
+}
+
+// GFA H line tags, custom tags are lower case
+func (self ProgramInfo) gfaTags() string {
+	tags := fmt.Sprintf("\tpn:Z:%s\tvn:Z:%s", self.Name, headerValue(self.VersionString()))
+	if self.CommandLine != "" {
+		tags += "\tcl:Z:" + headerValue(self.CommandLine)
+	}
+	return tags
+}
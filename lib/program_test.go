@@ -0,0 +1,28 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestProgramInfo_Headers(t *testing.T) {
+	saved := Program
+	defer func() { Program = saved }()
+	Program = ProgramInfo{Name: "PoaGo", Version: "v1.2.0", Revision: "abc123", CommandLine: "PoaGo call -f \"a\tb.fa\""}
+
+	assert.Equal(t, "v1.2.0 (abc123)", Program.VersionString())
+	assert.Equal(t, "@PG\tID:PoaGo\tPN:PoaGo\tVN:v1.2.0 (abc123)\tCL:PoaGo call -f \"a b.fa\"\n", Program.samHeader())
+
+	var buf bytes.Buffer
+	calls, _ := variantTestGraph().CallVariants(VariantOptionsDefault())
+	assert.Nil(t, calls.WriteVCF(&buf))
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "##source=PoaGo v1.2.0 (abc123)", lines[1])
+	assert.Equal(t, "##commandline=\"PoaGo call -f 'a b.fa'\"", lines[2])
+
+	buf.Reset()
+	assert.Nil(t, writerTestGraph().WriteGFA(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "H\tVN:Z:1.0\tpn:Z:PoaGo\tvn:Z:v1.2.0 (abc123)\tcl:Z:PoaGo call"))
+}
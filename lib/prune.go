@@ -0,0 +1,204 @@
+package PoaGo
+
+// PruneStats : what Prune removed and how many sequences it changed
+type PruneStats struct {
+	Nodes    int // nodes removed
+	Edges    int // edges removed
+	Rerouted int // sequences whose path changed
+	Cut      int // sequences whose path couldn't be rerouted in one piece, they are taken out of the graph
+}
+
+// support of each node, the number of sequences going through it
+func (self *PoaGraph) nodeSupport() []int {
+	support := make([]int, len(self.nodes))
+	for nodeId, node := range self.nodes {
+		if node != nil {
+			support[nodeId] = node.labelSet().count()
+		}
+	}
+	return support
+}
+
+// Prune removes the nodes and edges supported by fewer than minSupport sequences. The paths going
+// through them are rerouted: a removed node is replaced by the best supported node aligned to it,
+// or dropped if there is none, and two nodes of a path that are no longer linked are bridged by the
+// best supported path of remaining edges between them. The sequences of the records become what their
+// new paths spell. A sequence whose path can't be bridged, or has no node left, is taken out of the
+// graph rather than cut, the ids of the following sequences move down. Only edges that were already
+// in the graph are used so the order of the remaining nodes, and the alignment columns, stay
+func (self *PoaGraph) Prune(minSupport int) PruneStats {
+	stats := PruneStats{}
+	if minSupport <= 1 {
+		return stats
+	}
+	self.ensureSorted()
+
+	support := self.nodeSupport()
+	keepNode := func(nodeId int) bool {
+		return support[nodeId] >= minSupport
+	}
+	keepEdge := func(from, to int) bool {
+		edge, ok := self.nodes[from].outEdges[to]
+		return ok && keepNode(from) && keepNode(to) && edge.NbLabels() >= minSupport
+	}
+	replacement := func(nodeId int) int {
+		best := -1
+		for _, other := range self.nodes[nodeId].alignedTo {
+			if keepNode(other) && (best < 0 || support[other] > support[best] || support[other] == support[best] && other < best) {
+				best = other
+			}
+		}
+		return best
+	}
+	// the nodes between from and to on the path of kept edges with the most support, over the nodes
+	// ranked between them
+	bridge := func(from, to int) ([]int, bool) {
+		lo, hi := self.nodes[from].rank, self.nodes[to].rank
+		score, prevOf := map[int]int{from: 0}, make(map[int]int)
+		for _, nodeId := range self.nodeList[lo:intArrayMax([]int{hi, lo})] {
+			s, reached := score[nodeId]
+			if !reached {
+				continue
+			}
+			for _, next := range sortedKeys(self.nodes[nodeId].outEdges) {
+				if self.nodes[next].rank > hi || !keepEdge(nodeId, next) {
+					continue
+				}
+				if best, ok := score[next]; !ok || s+support[next] > best {
+					score[next], prevOf[next] = s+support[next], nodeId
+				}
+			}
+		}
+		if _, ok := score[to]; !ok {
+			return nil, false
+		}
+		between := make([]int, 0)
+		for nodeId := prevOf[to]; nodeId != from; nodeId = prevOf[nodeId] {
+			between = append([]int{nodeId}, between...)
+		}
+		return between, true
+	}
+
+	paths := make([][]int, len(self.records))
+	for seqId := range self.records {
+		oldPath := self.pathIds(seqId)
+		mapped := make([]int, 0, len(oldPath))
+		for _, nodeId := range oldPath {
+			if !keepNode(nodeId) {
+				nodeId = replacement(nodeId)
+			}
+			if nodeId >= 0 {
+				mapped = append(mapped, nodeId)
+			}
+		}
+
+		path := make([]int, 0, len(mapped))
+		for i, nodeId := range mapped {
+			if i > 0 && !keepEdge(path[len(path)-1], nodeId) {
+				between, ok := bridge(path[len(path)-1], nodeId)
+				if !ok {
+					path = nil
+					break
+				}
+				path = append(path, between...)
+			}
+			path = append(path, nodeId)
+		}
+		switch {
+		case len(path) == 0:
+			stats.Cut += 1
+		case !arrEqual(path, oldPath):
+			stats.Rerouted += 1
+			paths[seqId] = path
+		default:
+			paths[seqId] = path
+		}
+	}
+
+	// the sequences without a path are taken out, the others get consecutive ids again
+	records, keptPaths := make([]*SeqRecord, 0, len(self.records)), make([][]int, 0, len(paths))
+	for seqId, record := range self.records {
+		if paths[seqId] == nil {
+			continue
+		}
+		record.Id = len(records)
+		records, keptPaths = append(records, record), append(keptPaths, paths[seqId])
+	}
+	self.records = records
+
+	self.rebuildFromPaths(keptPaths, &stats)
+	return stats
+}
+
+// replaces the edges by the ones of the paths and removes the nodes that aren't on a path. The edges
+// of the paths must already be in the graph so the order stays topological
+func (self *PoaGraph) rebuildFromPaths(paths [][]int, stats *PruneStats) {
+	onPath := make([]bool, len(self.nodes))
+	for _, path := range paths {
+		for _, nodeId := range path {
+			onPath[nodeId] = true
+		}
+	}
+
+	nbEdges := self.nbEdges
+	for nodeId, node := range self.nodes {
+		if node == nil {
+			continue
+		}
+		if !onPath[nodeId] {
+			self.nodes[nodeId] = nil
+			self.nbNodes -= 1
+			stats.Nodes += 1
+			continue
+		}
+		node.inEdges = make(map[int]*Edge)
+		node.outEdges = make(map[int]*Edge)
+	}
+	for _, node := range self.nodes {
+		if node == nil {
+			continue
+		}
+		alignedTo := node.alignedTo[:0]
+		for _, other := range node.alignedTo {
+			if self.nodes[other] != nil {
+				alignedTo = append(alignedTo, other)
+			}
+		}
+		node.alignedTo = alignedTo
+	}
+
+	self.nbEdges = 0
+	for seqId, path := range paths {
+		record := self.records[seqId]
+		bases := make([]byte, len(path))
+		record.start = -1
+		for i, nodeId := range path {
+			bases[i] = self.nodes[nodeId].base[0]
+			if i == 0 {
+				record.start = nodeId
+			} else {
+				self.AddEdge(path[i-1], nodeId, seqId)
+			}
+		}
+		if len(bases) != len(record.Seq) {
+			// the qualities were for the bases of the read
+			record.Qual = ""
+		}
+		record.Seq = string(bases)
+	}
+	stats.Edges = nbEdges - self.nbEdges
+
+	nodeList := self.nodeList[:0]
+	for _, nodeId := range self.nodeList {
+		if self.nodes[nodeId] != nil {
+			nodeList = append(nodeList, nodeId)
+		}
+	}
+	self.nodeList = nodeList
+	self.needSort = false
+	self.updateRanks()
+
+	// the k-mers of the sequences changed
+	self.kmerIndex = make(map[string]bool)
+	self.nbIndexed = 0
+}
@@ -0,0 +1,82 @@
+package PoaGo
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func pruneTestGraph(reads ...string) *PoaGraph {
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("ACGTACGTAC", "r0", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	for i := 1; i < 4; i++ {
+		g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTACGTAC", fmt.Sprintf("r%d", i)))
+	}
+	for i, read := range reads {
+		g.AddSequenceAlignment(AlignStringToGraph(g, aln, read, fmt.Sprintf("x%d", i)))
+	}
+	return g
+}
+
+func TestPoaGraph_Prune(t *testing.T) {
+	// a mismatch and an insertion seen in a single read
+	g := pruneTestGraph("ACGTTCGTAC", "ACGTAGCGTAC")
+	assert.Equal(t, 12, g.NbNodes())
+	assert.Equal(t, PruneStats{}, g.Prune(1))
+	assert.Equal(t, 12, g.NbNodes())
+
+	stats := g.Prune(2)
+	assert.Equal(t, PruneStats{Nodes: 2, Edges: 4, Rerouted: 2}, stats)
+	assert.Nil(t, g.Validate())
+	assert.Equal(t, 10, g.NbNodes())
+	assert.Equal(t, 9, g.NbEdges())
+	for _, label := range []string{"x0", "x1"} {
+		seq, _ := g.Sequence(label)
+		assert.Equal(t, "ACGTACGTAC", seq)
+	}
+	clusters, _ := g.HaplotypeClusters(DefaultMaxFraction, 1)
+	assert.Equal(t, 1, len(clusters))
+	assert.Equal(t, "ACGTACGTAC", clusters[0].Sequence())
+
+	// the graph can still be aligned to
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	assert.Nil(t, g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTTCGTAC", "y")))
+	assert.Nil(t, g.Validate())
+}
+
+func TestPoaGraph_PruneBridge(t *testing.T) {
+	// a two base deletion is bridged by the two nodes of the other reads
+	g := pruneTestGraph("ACGTGTAC")
+	stats := g.Prune(2)
+	assert.Equal(t, PruneStats{Edges: 1, Rerouted: 1}, stats)
+	assert.Nil(t, g.Validate())
+	seq, _ := g.Sequence("x0")
+	assert.Equal(t, "ACGTACGTAC", seq)
+}
+
+func TestPoaGraph_PruneCut(t *testing.T) {
+	// two haplotypes and a read going from one to the other, nothing links them once the read is
+	// pruned, so it is taken out rather than cut
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("AAAACCCCGGGG", "c0", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	for i, read := range []string{"AAAACCCCGGGG", "AAAATTTTGGGG", "AAAATTTTGGGG", "AAAACCTTGGGG", "AAAATTTTGGGG"} {
+		assert.Nil(t, g.AddSequenceAlignment(AlignStringToGraph(g, aln, read, fmt.Sprintf("r%d", i))))
+	}
+	stats := g.Prune(2)
+	assert.Equal(t, PruneStats{Edges: 1, Cut: 1}, stats)
+	assert.Nil(t, g.Validate())
+	assert.Equal(t, 5, g.NbSequences())
+	_, ok := g.Record("r3")
+	assert.False(t, ok)
+	// the other reads keep their data, the ones after it move down
+	for i, rec := range g.Records() {
+		assert.Equal(t, i, rec.Id)
+		seq, _ := g.Sequence(rec.Name)
+		assert.Equal(t, rec.Seq, seq)
+	}
+	rec, _ := g.Record("r4")
+	assert.Equal(t, 4, rec.Id)
+	assert.Equal(t, "AAAATTTTGGGG", rec.Seq)
+}
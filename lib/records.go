@@ -0,0 +1,176 @@
+package PoaGo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SeqRecord : a sequence in the graph and what is known about it. The id is the index of the record
+// in the graph, edges refer to sequences by id
+type SeqRecord struct {
+	Id          int
+	Name        string
+	Description string
+	Seq         string            // as added to the graph, the reverse complement of the read if Reverse
+	Qual        string            // qualities in the same orientation as Seq, empty for fasta
+	Reverse     bool              // the read was added as its reverse complement
+	Weight      float64           // 1 unless given, a weight of 0 is kept only if set with SetWeight
+	ReadGroup   string            // read group id, empty if none
+	Sample      string            // sample name, empty if none
+	Tags        map[string]string // key=value pairs of the description
+	start       int               // first node of the path of the sequence
+	weightSet   bool              // Weight was given, even if 0
+}
+
+// SetWeight sets the weight of the record, unlike setting Weight a weight of 0 is kept when the record
+// is added to a graph
+func (self *SeqRecord) SetWeight(weight float64) {
+	self.Weight, self.weightSet = weight, true
+}
+
+// reserved description tags that fill the record fields instead of Tags
+const (
+	readGroupTag = "RG"
+	sampleTag    = "SM"
+	weightTag    = "weight"
+)
+
+// SeqRecordFromFastx makes a record from a fasta/fastq record. key=value words of the description
+// go to Tags, RG, SM and weight are the read group, sample and weight
+func SeqRecordFromFastx(rec Record) SeqRecord {
+	record := SeqRecord{Name: rec.Name, Description: rec.Description, Seq: rec.Seq, Qual: rec.Qual, Weight: 1.0}
+	for _, word := range strings.Fields(rec.Description) {
+		key, value, found := strings.Cut(word, "=")
+		if !found || key == "" {
+			continue
+		}
+		switch key {
+		case readGroupTag:
+			record.ReadGroup = value
+		case sampleTag:
+			record.Sample = value
+		case weightTag:
+			if weight, err := strconv.ParseFloat(value, 64); err == nil {
+				record.SetWeight(weight)
+				continue
+			}
+			fallthrough
+		default:
+			if record.Tags == nil {
+				record.Tags = make(map[string]string)
+			}
+			record.Tags[key] = value
+		}
+	}
+	return record
+}
+
+// adds a record to the table and gives it the next id, its path is set by the caller
+func (self *PoaGraph) addRecord(rec SeqRecord, reverse bool) *SeqRecord {
+	record := rec
+	record.Id = len(self.records)
+	record.start = -1
+	if record.Weight == 0 && !record.weightSet {
+		record.Weight = 1.0
+	}
+	if reverse && !record.Reverse {
+		record.Reverse = true
+		record.Qual = reverseString(record.Qual)
+	}
+	self.records = append(self.records, &record)
+	return &record
+}
+
+func reverseString(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// NbSequences returns the number of sequences in the graph
+func (self *PoaGraph) NbSequences() int {
+	return len(self.records)
+}
+
+// Records returns the records of the sequences in the graph, in the order they were added. Removing a
+// sequence doesn't change the returned slice, the ids of its records are updated
+func (self *PoaGraph) Records() []*SeqRecord {
+	return self.records
+}
+
+// Record returns the record of the first sequence with this name
+func (self *PoaGraph) Record(label string) (*SeqRecord, bool) {
+	for _, rec := range self.records {
+		if rec.Name == label {
+			return rec, true
+		}
+	}
+	return nil, false
+}
+
+// Path returns the node ids along the path of the first sequence with this name, in order
+func (self *PoaGraph) Path(label string) ([]int, bool) {
+	rec, ok := self.Record(label)
+	if !ok {
+		return nil, false
+	}
+	return self.pathIds(rec.Id), true
+}
+
+// NodeLabels returns the names of the sequences going through a node
+func (self *PoaGraph) NodeLabels(nodeId int) []string {
+	if !checkForNode(self, nodeId) {
+		return nil
+	}
+	node := self.nodes[nodeId]
+	labels := make([]string, 0)
+	node.labelSet().forEach(func(id int) { labels = append(labels, self.records[id].Name) })
+	return labels
+}
+
+// EdgeLabels returns the names of the sequences going through the edge from one node to the next,
+// nil if there is no such edge
+func (self *PoaGraph) EdgeLabels(fromId, toId int) []string {
+	if !checkForNode(self, fromId) {
+		return nil
+	}
+	node := self.nodes[fromId]
+	edge, ok := node.outEdges[toId]
+	if !ok {
+		return nil
+	}
+	labels := make([]string, 0, edge.NbLabels())
+	edge.labels.forEach(func(id int) { labels = append(labels, self.records[id].Name) })
+	return labels
+}
+
+// labels returns the names of the sequences, indexed by id
+func (self *PoaGraph) labels() []string {
+	labels := make([]string, len(self.records))
+	for i, rec := range self.records {
+		labels[i] = rec.Name
+	}
+	return labels
+}
+
+// ReadGroup : a read group of the sequences, with its sample
+type ReadGroup struct {
+	Id     string
+	Sample string
+}
+
+// ReadGroups returns the read groups of the sequences in the order they first appear
+func (self *PoaGraph) ReadGroups() []ReadGroup {
+	groups := make([]ReadGroup, 0)
+	seen := make(map[string]bool)
+	for _, rec := range self.records {
+		if rec.ReadGroup == "" || seen[rec.ReadGroup] {
+			continue
+		}
+		seen[rec.ReadGroup] = true
+		groups = append(groups, ReadGroup{Id: rec.ReadGroup, Sample: rec.Sample})
+	}
+	return groups
+}
@@ -0,0 +1,95 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSeqRecordFromFastx(t *testing.T) {
+	rec := SeqRecordFromFastx(Record{Name: "r1", Description: "first read RG=rg1 SM=s1 weight=2.5 umi=ACGT x=", Seq: "ACGT", Qual: "IIII"})
+	assert.Equal(t, "r1", rec.Name)
+	assert.Equal(t, "first read RG=rg1 SM=s1 weight=2.5 umi=ACGT x=", rec.Description)
+	assert.Equal(t, "rg1", rec.ReadGroup)
+	assert.Equal(t, "s1", rec.Sample)
+	assert.Equal(t, 2.5, rec.Weight)
+	assert.Equal(t, map[string]string{"umi": "ACGT", "x": ""}, rec.Tags)
+
+	rec = SeqRecordFromFastx(Record{Name: "r2", Description: "weight=heavy", Seq: "ACGT"})
+	assert.Equal(t, 1.0, rec.Weight)
+	assert.Equal(t, map[string]string{"weight": "heavy"}, rec.Tags)
+
+	rec = SeqRecordFromFastx(Record{Name: "r3", Description: "weight=0", Seq: "ACGT"})
+	assert.Equal(t, 0.0, rec.Weight)
+	assert.Nil(t, rec.Tags)
+}
+
+func TestPoaGraph_RecordWeight(t *testing.T) {
+	g := PoaGraphConstruct()
+	g.AddBaseRecord(SeqRecordFromFastx(Record{Name: "zero", Description: "weight=0", Seq: "ACGT"}))
+	g.AddBaseRecord(SeqRecord{Name: "unset", Seq: "ACGT"})
+	set := SeqRecord{Name: "set", Seq: "ACGT"}
+	set.SetWeight(0)
+	g.AddBaseRecord(set)
+	g.AddBaseRecord(SeqRecord{Name: "given", Seq: "ACGT", Weight: 2})
+
+	weights := make([]float64, 0)
+	for _, rec := range g.Records() {
+		weights = append(weights, rec.Weight)
+	}
+	assert.Equal(t, []float64{0, 1, 0, 2}, weights)
+}
+
+func TestPoaGraph_Records(t *testing.T) {
+	base := "GATTACAGGCATTCCA"
+	g := PoaGraphConstruct()
+	g.AddBaseRecord(SeqRecord{Name: "base", Seq: base, Qual: strings.Repeat("I", len(base)), ReadGroup: "rg1", Sample: "s1"})
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+
+	qual := "ABCDEFGHIJKLMNOP"
+	pA := AlignOrientedStringToGraph(g, aln, ReverseComplement(base), "rc", OrientAlign)
+	assert.True(t, pA.Reverse())
+	pA.SetRecord(SeqRecord{Name: "rc", Seq: ReverseComplement(base), Qual: qual, ReadGroup: "rg2", Sample: "s2"})
+	g.AddSequenceAlignment(pA)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, base, "plain"))
+
+	assert.Equal(t, 3, g.NbSequences())
+	records := g.Records()
+	for i, rec := range records {
+		assert.Equal(t, i, rec.Id)
+		assert.Equal(t, 1.0, rec.Weight)
+	}
+	assert.False(t, records[0].Reverse)
+	assert.True(t, records[1].Reverse)
+	assert.Equal(t, base, records[1].Seq)
+	assert.Equal(t, "PONMLKJIHGFEDCBA", records[1].Qual)
+	assert.Equal(t, "", records[2].Qual)
+
+	rec, ok := g.Record("rc")
+	assert.True(t, ok)
+	assert.Equal(t, "rg2", rec.ReadGroup)
+	_, ok = g.Record("missing")
+	assert.False(t, ok)
+
+	first, _ := g.Sequence("base")
+	assert.Equal(t, base, first)
+	for _, id := range g.nodes[0].SeqIds() {
+		assert.True(t, id >= 0 && id < 3)
+	}
+	assert.Equal(t, []string{"base", "rc", "plain"}, g.NodeLabels(0))
+	assert.Nil(t, g.NodeLabels(-1))
+
+	assert.Equal(t, []ReadGroup{{Id: "rg1", Sample: "s1"}, {Id: "rg2", Sample: "s2"}}, g.ReadGroups())
+
+	var buf bytes.Buffer
+	w, err := AlignmentWriterForFormat("sam", &buf)
+	assert.Nil(t, err)
+	opts := AlignmentOptionsDefault()
+	opts.Consensus = false
+	assert.Nil(t, g.WriteAlignment(w, opts))
+	sam := buf.String()
+	assert.Contains(t, sam, "@RG\tID:rg1\tSM:s1\n@RG\tID:rg2\tSM:s2\n")
+	assert.Contains(t, sam, "\tPONMLKJIHGFEDCBA\tRG:Z:rg2\n")
+	assert.Contains(t, sam, "\t"+strings.Repeat("I", len(base))+"\tRG:Z:rg1\n")
+}
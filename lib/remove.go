@@ -0,0 +1,101 @@
+package PoaGo
+
+import (
+	"fmt"
+)
+
+// RemoveSequence takes the first sequence with this label out of the graph, for reads found to be
+// chimeric or contaminants once the graph is built. The sequence is stripped from the edges along its
+// path, the edges and nodes no other sequence goes through are removed and the ids of the following
+// sequences move down by one. The remaining nodes keep their order, so the graph can be aligned to
+// without sorting it again
+func (self *PoaGraph) RemoveSequence(label string) error {
+	rec, ok := self.Record(label)
+	if !ok {
+		return fmt.Errorf("RemoveSequence: no sequence %v in the graph", label)
+	}
+	self.removeSequence(rec.Id, self.pathIds(rec.Id))
+	return nil
+}
+
+// takes the seqId-th sequence going through path out of the graph, see RemoveSequence. The path may
+// go through a node more than once, when it is taken out for closing a cycle
+func (self *PoaGraph) removeSequence(seqId int, path []int) {
+	for i := 1; i < len(path); i++ {
+		from, to := self.nodes[path[i-1]], self.nodes[path[i]]
+		outEdge, inEdge := from.outEdges[to.id], to.inEdges[from.id]
+		if outEdge == nil {
+			continue
+		}
+		outEdge.labels.remove(seqId)
+		inEdge.labels.remove(seqId)
+		if outEdge.NbLabels() == 0 {
+			delete(from.outEdges, to.id)
+			delete(to.inEdges, from.id)
+			self.nbEdges -= 1
+		}
+	}
+
+	// a new slice, the one returned by Records before stays as it was
+	records := make([]*SeqRecord, 0, len(self.records)-1)
+	records = append(records, self.records[:seqId]...)
+	self.records = append(records, self.records[seqId+1:]...)
+	startsAt := make(map[int]bool)
+	for i, record := range self.records {
+		record.Id = i
+		startsAt[record.start] = true
+	}
+	for _, node := range self.nodes {
+		if node == nil {
+			continue
+		}
+		for _, edge := range node.outEdges {
+			edge.labels.deleteId(seqId)
+		}
+		for _, edge := range node.inEdges {
+			edge.labels.deleteId(seqId)
+		}
+	}
+
+	// a node without edges is only in the graph if a sequence of length one goes through it
+	removed := make(map[int]bool)
+	for _, nodeId := range path {
+		node := self.nodes[nodeId]
+		if node == nil {
+			continue
+		}
+		if node.InDegree() == 0 && node.OutDegree() == 0 && !startsAt[nodeId] {
+			removed[nodeId] = true
+			self.nodes[nodeId] = nil
+			self.nbNodes -= 1
+		}
+	}
+	if len(removed) > 0 {
+		for _, node := range self.nodes {
+			if node == nil {
+				continue
+			}
+			alignedTo := node.alignedTo[:0]
+			for _, other := range node.alignedTo {
+				if !removed[other] {
+					alignedTo = append(alignedTo, other)
+				}
+			}
+			node.alignedTo = alignedTo
+		}
+		nodeList := self.nodeList[:0]
+		for _, nodeId := range self.nodeList {
+			if !removed[nodeId] {
+				nodeList = append(nodeList, nodeId)
+			}
+		}
+		self.nodeList = nodeList
+	}
+	if !self.needSort {
+		self.updateRanks()
+	}
+
+	// the k-mers of the sequence are indexed again without it
+	self.kmerIndex = make(map[string]bool)
+	self.nbIndexed = 0
+}
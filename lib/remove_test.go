@@ -0,0 +1,107 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLabelSet_deleteId(t *testing.T) {
+	s := make(labelSet, 0)
+	for _, id := range []int{0, 5, 63, 64, 70, 128} {
+		s.add(id)
+	}
+	s.deleteId(5)
+	assert.Equal(t, []int{0, 62, 63, 69, 127}, s.ids())
+	s.deleteId(62)
+	assert.Equal(t, []int{0, 62, 68, 126}, s.ids())
+	// an id that isn't in the set still moves the ones above it
+	s.deleteId(1)
+	assert.Equal(t, []int{0, 61, 67, 125}, s.ids())
+	s.deleteId(1000)
+	assert.Equal(t, []int{0, 61, 67, 125}, s.ids())
+}
+
+func TestPoaGraph_RemoveSequence(t *testing.T) {
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	reads := []string{"ACGTACGTAC", "ACGTTCGTAC", "GGGACGTAGCGTAC", "ACGTACGTAC"}
+	build := func(skip int) *PoaGraph {
+		g := PoaGraphConstruct()
+		for i, read := range reads {
+			if i == skip {
+				continue
+			}
+			if g.NbSequences() == 0 {
+				g.AddBaseSequence(read, string(rune('a'+i)), true)
+				continue
+			}
+			g.AddSequenceAlignment(AlignStringToGraph(g, aln, read, string(rune('a'+i))))
+		}
+		return g
+	}
+
+	// the read with the insertion and the ragged start
+	g := build(-1)
+	assert.Nil(t, g.RemoveSequence("c"))
+	assert.Nil(t, g.Validate())
+	without := build(2)
+	assert.Equal(t, without.NbNodes(), g.NbNodes())
+	assert.Equal(t, without.NbEdges(), g.NbEdges())
+	assert.Equal(t, 3, g.NbSequences())
+	for i, rec := range g.Records() {
+		assert.Equal(t, i, rec.Id)
+		seq, _ := g.Sequence(rec.Name)
+		assert.Equal(t, rec.Seq, seq)
+	}
+	assert.Equal(t, []string{"a", "b", "d"}, g.NodeLabels(g.records[0].start))
+	names, rows := g.GenerateAlignmentStrings()
+	assert.Equal(t, []string{"a", "b", "d", "Consensus0"}, names)
+	assert.Equal(t, []string{"ACGTACGTAC", "ACGTTCGTAC", "ACGTACGTAC", "ACGTACGTAC"}, rows)
+
+	// still good for more reads
+	assert.Nil(t, g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTACGTAC", "e")))
+	assert.Nil(t, g.Validate())
+	assert.EqualError(t, g.RemoveSequence("c"), "RemoveSequence: no sequence c in the graph")
+
+	// the first sequence, the others keep the nodes
+	g = build(-1)
+	nbNodes := g.NbNodes()
+	assert.Nil(t, g.RemoveSequence("a"))
+	assert.Nil(t, g.Validate())
+	assert.Equal(t, nbNodes, g.NbNodes())
+
+	// a sequence of one base has no edges
+	g = PoaGraphConstruct()
+	g.AddBaseSequence("A", "one", true)
+	g.AddBaseSequence("CG", "two", true)
+	assert.Nil(t, g.RemoveSequence("two"))
+	assert.Nil(t, g.Validate())
+	assert.Equal(t, 1, g.NbNodes())
+	assert.Nil(t, g.RemoveSequence("one"))
+	assert.Nil(t, g.Validate())
+	assert.Equal(t, 0, g.NbNodes())
+}
+
+func TestPoaGraph_RemoveSequenceWhileIterating(t *testing.T) {
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("ACGTACGT", "r0", true)
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	for _, label := range []string{"x1", "r2", "x3", "x4", "r5"} {
+		assert.Nil(t, g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTTCGT", label)))
+	}
+
+	// the slice being iterated keeps its records while they are removed
+	seen := make([]string, 0)
+	for _, rec := range g.Records() {
+		seen = append(seen, rec.Name)
+		if rec.Name[0] == 'x' {
+			assert.Nil(t, g.RemoveSequence(rec.Name))
+		}
+	}
+	assert.Equal(t, []string{"r0", "x1", "r2", "x3", "x4", "r5"}, seen)
+	assert.Equal(t, 3, g.NbSequences())
+	for i, rec := range g.Records() {
+		assert.Equal(t, i, rec.Id)
+		assert.Equal(t, "r", rec.Name[:1])
+	}
+	assert.Nil(t, g.Validate())
+}
@@ -0,0 +1,232 @@
+package PoaGo
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+type ReportOptions struct {
+	Title        string
+	ReadsUsed    int         // reads added to the graph
+	ReadsSkipped int         // reads that didn't align to the graph
+	Scheme       ColorScheme // residue colors of the alignment
+	MaxFraction  float64     // see AllConsensuses
+	Width        int         // number of alignment columns per block
+}
+
+func ReportOptionsDefault() ReportOptions {
+	return ReportOptions{Title: "PoaGo alignment", Scheme: NucleotideScheme, MaxFraction: DefaultMaxFraction, Width: 100}
+}
+
+func (self *PoaGraph) NbNodes() int {
+	return self.nbNodes
+}
+
+func (self *PoaGraph) NbEdges() int {
+	return self.nbEdges
+}
+
+// data given to the report template
+type reportConsensus struct {
+	Name     string
+	Sequence string
+	Support  int
+	Labels   []string
+}
+
+type reportData struct {
+	Opts       ReportOptions
+	NbNodes    int
+	NbEdges    int
+	NbColumns  int
+	Consensus  []reportConsensus
+	Styles     template.CSS
+	Blocks     []template.HTML
+	Coverage   template.HTML
+	Entropy    template.HTML
+	Unassigned []string
+}
+
+const reportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Opts.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table.stats td { padding: 0.1em 1em 0.1em 0; }
+pre { font-family: monospace; font-size: 13px; line-height: 1.3; }
+.seq { word-break: break-all; font-family: monospace; }
+.diff { outline: 1px solid #000; font-weight: bold; }
+.name { color: #555; }
+{{.Styles}}
+</style>
+</head>
+<body>
+<h1>{{.Opts.Title}}</h1>
+
+<h2>Summary</h2>
+<table class="stats">
+<tr><td>Reads used</td><td>{{.Opts.ReadsUsed}}</td></tr>
+<tr><td>Reads skipped</td><td>{{.Opts.ReadsSkipped}}</td></tr>
+<tr><td>Graph nodes</td><td>{{.NbNodes}}</td></tr>
+<tr><td>Graph edges</td><td>{{.NbEdges}}</td></tr>
+<tr><td>Alignment columns</td><td>{{.NbColumns}}</td></tr>
+<tr><td>Consensus sequences</td><td>{{len .Consensus}}</td></tr>
+</table>
+
+<h2>Consensus sequences</h2>
+{{range .Consensus}}<h3>{{.Name}} ({{.Support}} reads)</h3>
+<p class="seq">{{.Sequence}}</p>
+<p>Supporting reads: {{range $i, $l := .Labels}}{{if $i}}, {{end}}{{$l}}{{end}}</p>
+{{end}}{{if .Unassigned}}<p>Reads not assigned to a consensus: {{range $i, $l := .Unassigned}}{{if $i}}, {{end}}{{$l}}{{end}}</p>
+{{end}}
+<h2>Coverage</h2>
+{{.Coverage}}
+
+<h2>Entropy</h2>
+{{.Entropy}}
+
+<h2>Alignment</h2>
+{{range .Blocks}}<pre>{{.}}</pre>
+{{end}}</body>
+</html>
+`
+
+// css class of a residue color
+func colorClass(color string) string {
+	return "c" + strings.TrimPrefix(color, "#")
+}
+
+func (self ColorScheme) css() template.CSS {
+	colors := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, color := range self.colors {
+		if !seen[color] {
+			seen[color] = true
+			colors = append(colors, color)
+		}
+	}
+	sort.Strings(colors)
+	var b strings.Builder
+	for _, color := range colors {
+		fmt.Fprintf(&b, ".%s { background: %s; }\n", colorClass(color), color)
+	}
+	return template.CSS(b.String())
+}
+
+// the alignment in blocks of width columns, residues are colored with the scheme and the ones that
+// differ from the first consensus are outlined
+func (self *collectAlignmentWriter) blocks(scheme ColorScheme, width int) []template.HTML {
+	reference := ""
+	if len(self.header.Consensus) > 0 {
+		reference = self.header.Consensus[0].Seq
+	}
+	nbReads := len(self.rows) - len(self.header.Consensus)
+
+	blocks := make([]template.HTML, 0)
+	for start := 0; start < self.header.NbColumns; start += width {
+		end := min(start+width, self.header.NbColumns)
+		var b strings.Builder
+		for i, row := range self.rows {
+			fmt.Fprintf(&b, "<span class=\"name\">%s</span>  ",
+				template.HTMLEscapeString(fmt.Sprintf("%-*s", self.header.NameWidth, row.DisplayName())))
+			for col := start; col < end; col++ {
+				c := row.Seq[col]
+				classes := make([]string, 0, 2)
+				if color := scheme.Color(c); color != "" {
+					classes = append(classes, colorClass(color))
+				}
+				if i < nbReads && c != '-' && reference != "" && !strings.EqualFold(string(c), reference[col:col+1]) {
+					classes = append(classes, "diff")
+				}
+				if len(classes) == 0 {
+					b.WriteString(template.HTMLEscapeString(string(c)))
+					continue
+				}
+				fmt.Fprintf(&b, "<span class=\"%s\">%s</span>", strings.Join(classes, " "),
+					template.HTMLEscapeString(string(c)))
+			}
+			b.WriteString("\n")
+		}
+		blocks = append(blocks, template.HTML(b.String()))
+	}
+	return blocks
+}
+
+// inline SVG bar plot of one value per column
+func svgColumnPlot(values []float64, maxValue float64, label, color string) template.HTML {
+	const height, barWidth, margin = 120, 4, 40
+	plotWidth := len(values) * barWidth
+	if maxValue <= 0 {
+		maxValue = 1
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n",
+		plotWidth+margin+10, height+30)
+	fmt.Fprintf(&b, "<text x=\"0\" y=\"12\" font-size=\"11\">%s</text>\n", template.HTMLEscapeString(label))
+	fmt.Fprintf(&b, "<text x=\"0\" y=\"%d\" font-size=\"11\">%.3g</text>\n", 30, maxValue)
+	fmt.Fprintf(&b, "<text x=\"0\" y=\"%d\" font-size=\"11\">0</text>\n", height+20)
+	fmt.Fprintf(&b, "<line x1=\"%d\" y1=\"20\" x2=\"%d\" y2=\"%d\" stroke=\"#888\"/>\n", margin-2, margin-2, height+20)
+	for col, value := range values {
+		h := value / maxValue * height
+		fmt.Fprintf(&b, "<rect x=\"%d\" y=\"%.2f\" width=\"%d\" height=\"%.2f\" fill=\"%s\"><title>column %d: %.3g</title></rect>\n",
+			margin+col*barWidth, float64(height+20)-h, barWidth, h, color, col+1, value)
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// WriteHTMLReport writes a single self-contained html page with summary statistics, the consensus
+// sequences and the reads supporting them, per column coverage and entropy plots and the colored
+// alignment
+func (self *PoaGraph) WriteHTMLReport(w io.Writer, opts ReportOptions) error {
+	if opts.Width <= 0 {
+		opts.Width = ReportOptionsDefault().Width
+	}
+
+	aw := &collectAlignmentWriter{}
+	alnOpts := AlignmentOptionsDefault()
+	alnOpts.MaxFraction = opts.MaxFraction
+	if err := self.WriteAlignment(aw, alnOpts); err != nil {
+		return err
+	}
+
+	data := reportData{Opts: opts, NbNodes: self.NbNodes(), NbEdges: self.NbEdges(),
+		NbColumns: aw.header.NbColumns, Styles: opts.Scheme.css(), Blocks: aw.blocks(opts.Scheme, opts.Width)}
+
+	assigned := make(map[string]bool)
+	for i, cluster := range self.consensusRounds(opts.MaxFraction) {
+		data.Consensus = append(data.Consensus, reportConsensus{Name: fmt.Sprintf("%s%v", alnOpts.ConsensusPrefix, i),
+			Sequence: cluster.Sequence(), Support: cluster.Support, Labels: cluster.Labels})
+		for _, label := range cluster.Labels {
+			assigned[label] = true
+		}
+	}
+	for _, label := range self.labels() {
+		if !assigned[label] {
+			data.Unassigned = append(data.Unassigned, label)
+		}
+	}
+
+	stats := self.ColumnStatistics()
+	coverage := make([]float64, len(stats))
+	entropy := make([]float64, len(stats))
+	maxEntropy := 0.0
+	for i, s := range stats {
+		coverage[i] = float64(s.Depth)
+		entropy[i] = s.Entropy
+		maxEntropy = max(maxEntropy, s.Entropy)
+	}
+	data.Coverage = svgColumnPlot(coverage, float64(len(self.records)), "reads per column", "#4070c0")
+	data.Entropy = svgColumnPlot(entropy, maxEntropy, "entropy (bits) per column", "#c05040")
+
+	t, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
@@ -0,0 +1,43 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPoaGraph_WriteHTMLReport(t *testing.T) {
+	g := writerTestGraph()
+	opts := ReportOptionsDefault()
+	opts.Title = "<test>"
+	opts.ReadsUsed, opts.ReadsSkipped = 2, 1
+	var buf bytes.Buffer
+	assert.Nil(t, g.WriteHTMLReport(&buf, opts))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(t, out, "<title>&lt;test&gt;</title>")
+	assert.Contains(t, out, "<tr><td>Reads skipped</td><td>1</td></tr>")
+	assert.Contains(t, out, "<tr><td>Graph nodes</td><td>4</td></tr>")
+	assert.Contains(t, out, "<tr><td>Graph edges</td><td>4</td></tr>")
+	assert.Contains(t, out, "<h3>Consensus0 (2 reads)</h3>")
+	assert.Contains(t, out, "Supporting reads: base, new")
+	assert.Equal(t, 2, strings.Count(out, "<svg "))
+	assert.Contains(t, out, ".c00c000 { background: #00c000; }")
+	// no external assets
+	assert.NotContains(t, out, "src=")
+	assert.NotContains(t, out, "href=")
+}
+
+func TestReportAlignmentWriter_Blocks(t *testing.T) {
+	aw := &collectAlignmentWriter{}
+	consensus := AlignedRow{Name: "Consensus0", Seq: "ACG"}
+	aw.Begin(AlignmentHeader{NbColumns: 3, NbRows: 1, NameWidth: 10, Consensus: []AlignedRow{consensus}})
+	aw.WriteRow(AlignedRow{Name: "r<1>", Seq: "AT-"})
+	aw.WriteConsensus(consensus)
+	blocks := aw.blocks(NoScheme, 2)
+	assert.Equal(t, 2, len(blocks))
+	assert.Equal(t, "<span class=\"name\">r&lt;1&gt;      </span>  A<span class=\"diff\">T</span>\n"+
+		"<span class=\"name\">Consensus0</span>  AC\n", string(blocks[0]))
+}
@@ -0,0 +1,157 @@
+package PoaGo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SAM flags used to read the mappings
+const (
+	samUnmapped      = 0x4
+	samReverse       = 0x10
+	samSecondary     = 0x100
+	samSupplementary = 0x800
+)
+
+// ReadSAM reads the primary mappings of a SAM file, with the target lengths from the @SQ header lines.
+// Secondary and supplementary mappings are left out so each read is used once. The mappings are
+// converted to the PAF conventions: 0-based intervals, the query interval on the read as given and the
+// CIGAR without its clips. The reads are returned as they were sequenced, reads with hard clips or
+// without a sequence are left out
+func ReadSAM(r io.Reader) ([]ReadMapping, []SeqRecord, error) {
+	mappings, reads := make([]ReadMapping, 0), make([]SeqRecord, 0)
+	targetLens := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	lineNb := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		lineNb += 1
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if line[0] == '@' {
+			if !strings.HasPrefix(line, "@SQ\t") {
+				continue
+			}
+			name, length := "", -1
+			for _, field := range strings.Split(line, "\t")[1:] {
+				switch {
+				case strings.HasPrefix(field, "SN:"):
+					name = field[3:]
+				case strings.HasPrefix(field, "LN:"):
+					length, _ = strconv.Atoi(field[3:])
+				}
+			}
+			if name == "" || length < 0 {
+				return nil, nil, &ParseError{Line: lineNb, Msg: "@SQ line without SN or LN"}
+			}
+			targetLens[name] = length
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 11 {
+			return nil, nil, &ParseError{Line: lineNb, Msg: fmt.Sprintf("expected 11 tab separated columns, got %d", len(fields))}
+		}
+		flag, err1 := strconv.Atoi(fields[1])
+		pos, err2 := strconv.Atoi(fields[3])
+		mapQ, err3 := strconv.Atoi(fields[4])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, nil, &ParseError{Line: lineNb, Msg: "FLAG, POS and MAPQ should be integers"}
+		}
+		cigar, err := ParseCigar(fields[5])
+		if err != nil {
+			return nil, nil, &ParseError{Line: lineNb, Msg: err.Error()}
+		}
+		seq, qual := fields[9], fields[10]
+		reverse := flag&samReverse != 0
+
+		// the clips at both ends, in the orientation of the target
+		leading, trailing, hardClipped := 0, 0, false
+		for len(cigar) > 0 && (cigar[0].Op == 'S' || cigar[0].Op == 'H') {
+			leading += cigar[0].Len
+			hardClipped = hardClipped || cigar[0].Op == 'H'
+			cigar = cigar[1:]
+		}
+		for len(cigar) > 0 && (cigar[len(cigar)-1].Op == 'S' || cigar[len(cigar)-1].Op == 'H') {
+			trailing += cigar[len(cigar)-1].Len
+			hardClipped = hardClipped || cigar[len(cigar)-1].Op == 'H'
+			cigar = cigar[:len(cigar)-1]
+		}
+		queryLen, targetLen := cigarLengths(cigar)
+		if fields[5] != "*" && seq != "*" && len(seq) != queryLen+leading+trailing && !hardClipped {
+			return nil, nil, &ParseError{Line: lineNb, Msg: fmt.Sprintf("the CIGAR goes over %d bases, the sequence has %d", queryLen+leading+trailing, len(seq))}
+		}
+
+		if flag&(samSecondary|samSupplementary) != 0 {
+			continue
+		}
+		if seq != "*" && !hardClipped {
+			read := SeqRecord{Name: fields[0], Seq: seq}
+			if qual != "*" {
+				read.Qual = qual
+			}
+			if reverse {
+				read.Seq, read.Qual = ReverseComplement(read.Seq), reverseString(read.Qual)
+			}
+			reads = append(reads, read)
+		}
+		if flag&samUnmapped != 0 || fields[2] == "*" || len(cigar) == 0 {
+			continue
+		}
+
+		targetLength, ok := targetLens[fields[2]]
+		if !ok {
+			return nil, nil, &ParseError{Line: lineNb, Msg: fmt.Sprintf("no @SQ line for %v", fields[2])}
+		}
+		m := ReadMapping{QueryName: fields[0], QueryLen: queryLen + leading + trailing, Reverse: reverse,
+			TargetName: fields[2], TargetLen: targetLength, TargetStart: pos - 1, MapQ: mapQ, Cigar: cigar}
+		m.TargetEnd = m.TargetStart + targetLen
+		m.QueryStart, m.QueryEnd = leading, m.QueryLen-trailing
+		if reverse {
+			m.QueryStart, m.QueryEnd = trailing, m.QueryLen-leading
+		}
+		if m.TargetStart < 0 || m.TargetEnd > m.TargetLen {
+			return nil, nil, &ParseError{Line: lineNb, Msg: "mapping interval outside of the target"}
+		}
+		m.Matches, m.BlockLen = samMatches(cigar, fields[11:])
+		mappings = append(mappings, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return mappings, reads, nil
+}
+
+// the number of matching bases and the length of the alignment as PAF has them: the matches are the =
+// operations, or the aligned bases less the mismatches of the NM tag (edits without the gaps)
+func samMatches(cigar []CigarOp, tags []string) (int, int) {
+	aligned, gaps, equal, hasEqual := 0, 0, 0, false
+	for _, op := range cigar {
+		switch op.Op {
+		case 'M', 'X':
+			aligned += op.Len
+		case '=':
+			aligned += op.Len
+			equal += op.Len
+			hasEqual = true
+		case 'I', 'D':
+			gaps += op.Len
+		}
+	}
+	if hasEqual {
+		return equal, aligned + gaps
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "NM:i:") {
+			if nm, err := strconv.Atoi(tag[5:]); err == nil {
+				return aligned - (nm - gaps), aligned + gaps
+			}
+		}
+	}
+	return aligned, aligned + gaps
+}
@@ -0,0 +1,50 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReadSAM(t *testing.T) {
+	input := "@HD\tVN:1.6\n" +
+		"@SQ\tSN:ctg1\tLN:100\n" +
+		"read1\t0\tctg1\t11\t60\t2S5M1I2M\t*\t0\t0\tTTACGTAGCA\tABCDEFGHIJ\tNM:i:2\n" +
+		"read2\t16\tctg1\t21\t30\t3M1D2M1S\t*\t0\t0\tACGTAC\t*\n" +
+		"read2\t272\tctg1\t51\t0\t5M\t*\t0\t0\t*\t*\n" +
+		"read1\t2048\tctg1\t71\t60\t3H5M\t*\t0\t0\tACGTA\t*\n" +
+		"read3\t4\t*\t0\t0\t*\t*\t0\t0\tGGGG\tIIII\n"
+	mappings, reads, err := ReadSAM(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Equal(t, []ReadMapping{
+		{QueryName: "read1", QueryLen: 10, QueryStart: 2, QueryEnd: 10, TargetName: "ctg1", TargetLen: 100,
+			TargetStart: 10, TargetEnd: 17, Matches: 6, BlockLen: 8, MapQ: 60, Cigar: []CigarOp{{'M', 5}, {'I', 1}, {'M', 2}}},
+		// on the reverse strand the clip at the end is at the start of the read
+		{QueryName: "read2", QueryLen: 6, QueryStart: 1, QueryEnd: 6, Reverse: true, TargetName: "ctg1", TargetLen: 100,
+			TargetStart: 20, TargetEnd: 26, Matches: 5, BlockLen: 6, MapQ: 30, Cigar: []CigarOp{{'M', 3}, {'D', 1}, {'M', 2}}},
+	}, mappings)
+	// only the primary mappings and their reads as they were sequenced, the secondary and supplementary
+	// mappings would count the reads more than once
+	assert.Equal(t, []SeqRecord{
+		{Name: "read1", Seq: "TTACGTAGCA", Qual: "ABCDEFGHIJ"},
+		{Name: "read2", Seq: "GTACGT"},
+		{Name: "read3", Seq: "GGGG", Qual: "IIII"},
+	}, reads)
+}
+
+func TestReadSAM_Errors(t *testing.T) {
+	for _, input := range []string{
+		"@SQ\tSN:ctg1\n",
+		"read1\t0\tctg1\t1\t60\t4M\t*\t0\t0\tACGT\n",
+		"read1\tx\tctg1\t1\t60\t4M\t*\t0\t0\tACGT\t*\n",
+		"read1\t0\tctg1\t1\t60\t4Q\t*\t0\t0\tACGT\t*\n",
+		"read1\t0\tctg1\t1\t60\t5M\t*\t0\t0\tACGT\t*\n",
+		"read1\t0\tctg2\t1\t60\t4M\t*\t0\t0\tACGT\t*\n",
+		"read1\t0\tctg1\t98\t60\t4M\t*\t0\t0\tACGT\t*\n",
+	} {
+		_, _, err := ReadSAM(strings.NewReader("@SQ\tSN:ctg1\tLN:100\n" + input))
+		if assert.NotNil(t, err, input) {
+			assert.Equal(t, 2, err.(*ParseError).Line)
+		}
+	}
+}
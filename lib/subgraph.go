@@ -0,0 +1,132 @@
+package PoaGo
+
+import (
+	"fmt"
+)
+
+// SubgraphByLabels returns a new graph with only the sequences with these labels, the nodes and edges
+// they go through and the alignedTo links between those nodes. All the sequences with a label are
+// kept, e.g. the reads of one sample from Records
+func (self *PoaGraph) SubgraphByLabels(labels []string) (*PoaGraph, error) {
+	wanted := make(map[string]bool)
+	for _, label := range labels {
+		wanted[label] = true
+	}
+	found := make(map[string]bool)
+	seqIds, paths, offsets := make([]int, 0), make([][]int, 0), make([]int, 0)
+	for seqId, rec := range self.records {
+		if wanted[rec.Name] {
+			found[rec.Name] = true
+			seqIds = append(seqIds, seqId)
+			paths = append(paths, self.pathIds(seqId))
+			offsets = append(offsets, 0)
+		}
+	}
+	for _, label := range labels {
+		if !found[label] {
+			return nil, fmt.Errorf("SubgraphByLabels: no sequence %v in the graph", label)
+		}
+	}
+	return self.subgraph(seqIds, paths, offsets), nil
+}
+
+// SubgraphByRegion returns a new graph with the part of the graph between two positions of the first
+// consensus, 0-based with the end excluded: the nodes in the alignment columns from the one of the
+// start base to the one of the last base, and the pieces of the sequences going through them. A
+// sequence leaving and coming back to the region keeps its longest piece
+func (self *PoaGraph) SubgraphByRegion(start, end int) (*PoaGraph, error) {
+	clusters := self.consensusRounds(self.maxFraction)
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("SubgraphByRegion: no consensus")
+	}
+	consensus := clusters[0].Path
+	if start < 0 || end > len(consensus) || start >= end {
+		return nil, fmt.Errorf("SubgraphByRegion: region %v-%v is outside the consensus of length %v", start, end, len(consensus))
+	}
+	columnIndex, _ := self.columnIndex()
+	first, last := columnIndex[consensus[start]], columnIndex[consensus[end-1]]
+	inRegion := func(nodeId int) bool {
+		col := columnIndex[nodeId]
+		return col >= first && col <= last
+	}
+
+	seqIds, paths, offsets := make([]int, 0), make([][]int, 0), make([]int, 0)
+	for seqId := range self.records {
+		path := self.pathIds(seqId)
+		bestStart, bestLen := 0, 0
+		for i := 0; i < len(path); {
+			if !inRegion(path[i]) {
+				i += 1
+				continue
+			}
+			j := i
+			for j < len(path) && inRegion(path[j]) {
+				j += 1
+			}
+			if j-i > bestLen {
+				bestStart, bestLen = i, j-i
+			}
+			i = j
+		}
+		if bestLen > 0 {
+			seqIds = append(seqIds, seqId)
+			paths = append(paths, path[bestStart:bestStart+bestLen])
+			offsets = append(offsets, bestStart)
+		}
+	}
+	if len(seqIds) == 0 {
+		return nil, fmt.Errorf("SubgraphByRegion: no sequence in region %v-%v", start, end)
+	}
+	return self.subgraph(seqIds, paths, offsets), nil
+}
+
+// a new graph with the sequences seqIds going through paths, which are pieces of their paths in this
+// graph starting at offsets. Nodes get new ids in the order of this graph, which is still topological
+func (self *PoaGraph) subgraph(seqIds []int, paths [][]int, offsets []int) *PoaGraph {
+	self.ensureSorted()
+	g := PoaGraphConstruct()
+	g.maxFraction = self.maxFraction
+
+	newIds := make(map[int]int)
+	for _, path := range paths {
+		for _, nodeId := range path {
+			newIds[nodeId] = -1
+		}
+	}
+	for _, nodeId := range self.nodeList {
+		if _, ok := newIds[nodeId]; ok {
+			newIds[nodeId] = g.AddNode(self.nodes[nodeId].base)
+		}
+	}
+	for nodeId, newId := range newIds {
+		for _, other := range self.nodes[nodeId].alignedTo {
+			if otherId, ok := newIds[other]; ok {
+				g.nodes[newId].alignedTo = append(g.nodes[newId].alignedTo, otherId)
+			}
+		}
+	}
+
+	for i, seqId := range seqIds {
+		rec := *self.records[seqId]
+		if rec.Tags != nil {
+			rec.Tags = make(map[string]string)
+			for key, value := range self.records[seqId].Tags {
+				rec.Tags[key] = value
+			}
+		}
+		path := paths[i]
+		if len(rec.Qual) == len(rec.Seq) {
+			rec.Qual = rec.Qual[offsets[i] : offsets[i]+len(path)]
+		}
+		rec.Seq = rec.Seq[offsets[i] : offsets[i]+len(path)]
+		record := g.addRecord(rec, false)
+		record.start = newIds[path[0]]
+		for j := 1; j < len(path); j++ {
+			g.AddEdge(newIds[path[j-1]], newIds[path[j]], record.Id)
+		}
+	}
+
+	g.needSort = false
+	g.updateRanks()
+	return g
+}
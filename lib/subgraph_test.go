@@ -0,0 +1,64 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPoaGraph_SubgraphByLabels(t *testing.T) {
+	g := variantTestGraph()
+	sub, err := g.SubgraphByLabels([]string{"r4", "r1"})
+	assert.Nil(t, err)
+	assert.Nil(t, sub.Validate())
+	assert.Equal(t, 2, sub.NbSequences())
+	names, rows := sub.GenerateAlignmentStrings()
+	assert.Equal(t, []string{"r1", "r4", "Consensus0"}, names)
+	assert.Equal(t, "ACGTACTGT", rows[0])
+	assert.Equal(t, "ACGTA--GT", rows[1])
+	// the mismatch of r3 isn't in the subgraph
+	assert.Equal(t, 9, sub.NbNodes())
+
+	// the sample of the reads goes with them
+	g.records[1].Sample = "s1"
+	sub, _ = g.SubgraphByLabels([]string{"r2", "r3"})
+	rec, _ := sub.Record("r2")
+	assert.Equal(t, "s1", rec.Sample)
+	assert.Equal(t, 10, sub.NbNodes())
+	for _, node := range sub.nodes {
+		for _, other := range node.alignedTo {
+			assert.Contains(t, sub.nodes[other].alignedTo, node.id)
+		}
+	}
+
+	_, err = g.SubgraphByLabels([]string{"r1", "nope"})
+	assert.EqualError(t, err, "SubgraphByLabels: no sequence nope in the graph")
+}
+
+func TestPoaGraph_SubgraphByRegion(t *testing.T) {
+	g := variantTestGraph()
+	g.records[0].Qual = "ABCDEFGHI"
+	sub, err := g.SubgraphByRegion(1, 4)
+	assert.Nil(t, err)
+	assert.Nil(t, sub.Validate())
+	names, rows := sub.GenerateAlignmentStrings()
+	assert.Equal(t, []string{"r1", "r2", "r3", "r4", "r5", "Consensus0"}, names)
+	assert.Equal(t, []string{"CGT", "CGT", "TGT", "CGT", "CGT", "CGT"}, rows)
+	rec, _ := sub.Record("r1")
+	assert.Equal(t, "BCD", rec.Qual)
+
+	// the deletion of r4 is in the region, the bases around it are
+	sub, _ = g.SubgraphByRegion(4, 8)
+	assert.Nil(t, sub.Validate())
+	_, rows = sub.GenerateAlignmentStrings()
+	assert.Equal(t, []string{"ACTG", "ACTG", "ACTG", "A--G", "ACTG", "ACTG"}, rows)
+
+	// sub-MSA of a subgraph can be aligned to
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	assert.Nil(t, sub.AddSequenceAlignment(AlignStringToGraph(sub, aln, "ACTG", "new")))
+	assert.Nil(t, sub.Validate())
+
+	for _, region := range [][2]int{{-1, 2}, {3, 3}, {0, 10}} {
+		_, err = g.SubgraphByRegion(region[0], region[1])
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,193 @@
+package PoaGo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UmiSource : where the UMI of a read is found
+type UmiSource int
+
+const (
+	UmiFromName     UmiSource = iota // in the read name, e.g. read1_ACGTACGT
+	UmiFromSequence                  // in the read sequence, the match is trimmed from the read
+)
+
+func ParseUmiSource(source string) (UmiSource, error) {
+	switch strings.ToLower(source) {
+	case "", "name":
+		return UmiFromName, nil
+	case "seq", "sequence":
+		return UmiFromSequence, nil
+	default:
+		return UmiFromName, errors.New(fmt.Sprintf("Unknown UMI source %v, should be name or seq", source))
+	}
+}
+
+// the UMI appended to the read name after an underscore, as UMI-tools writes it
+const DefaultUmiPattern = `_([ACGTN]+)$`
+
+// the highest quality given to a consensus base
+const maxConsensusQuality = 60
+
+type UmiOptions struct {
+	Pattern      *regexp.Regexp // the first group of the match is the UMI, the whole match if it has no group
+	Source       UmiSource
+	MaxEdits     int // UMIs at most this many edits from the UMI of a group join it
+	MinGroupSize int // groups with fewer reads don't get a consensus
+}
+
+func UmiOptionsDefault() UmiOptions {
+	return UmiOptions{Pattern: regexp.MustCompile(DefaultUmiPattern), Source: UmiFromName, MaxEdits: 1, MinGroupSize: 3}
+}
+
+// UmiGroup : the reads with the same UMI, give or take a few errors. Umi is the most frequent UMI of
+// the group, Seq and Qual its consensus once Consensus is called
+type UmiGroup struct {
+	Umi     string
+	Umis    []string // all the UMIs grouped, the most frequent first
+	Records []SeqRecord
+	Seq     string
+	Qual    string
+}
+
+// ExtractUmi finds the UMI of a read. When it is in the sequence, the whole match is trimmed from the
+// sequence and the qualities of the returned record. Returns false if the pattern doesn't match
+func ExtractUmi(rec SeqRecord, opts UmiOptions) (string, SeqRecord, bool) {
+	text := rec.Name
+	if opts.Source == UmiFromSequence {
+		text = rec.Seq
+	}
+	match := opts.Pattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return "", rec, false
+	}
+	umi := text[match[0]:match[1]]
+	if len(match) > 2 && match[2] >= 0 {
+		umi = text[match[2]:match[3]]
+	}
+	if opts.Source == UmiFromSequence {
+		if len(rec.Qual) == len(rec.Seq) {
+			rec.Qual = rec.Qual[:match[0]] + rec.Qual[match[1]:]
+		}
+		rec.Seq = rec.Seq[:match[0]] + rec.Seq[match[1]:]
+	}
+	return strings.ToUpper(umi), rec, true
+}
+
+// GroupByUmi groups the reads by their UMI, umis[i] being the UMI of records[i]. The UMIs are taken
+// from the most to the least frequent, each joins the first group whose UMI is at most maxEdits away
+// or starts a new group, so errors in the UMI of a few reads don't split a group. Groups come out
+// in the order they were started and keep the order of their reads
+func GroupByUmi(records []SeqRecord, umis []string, maxEdits int) []*UmiGroup {
+	if len(records) != len(umis) {
+		panic("number of records != number of UMIs")
+	}
+	counts := make(map[string]int)
+	for _, umi := range umis {
+		counts[umi] += 1
+	}
+	distinct := make([]string, 0, len(counts))
+	for umi := range counts {
+		distinct = append(distinct, umi)
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		if counts[distinct[i]] != counts[distinct[j]] {
+			return counts[distinct[i]] > counts[distinct[j]]
+		}
+		return distinct[i] < distinct[j]
+	})
+
+	groups := make([]*UmiGroup, 0)
+	groupOf := make(map[string]*UmiGroup)
+	for _, umi := range distinct {
+		for _, group := range groups {
+			if EditDistance(umi, group.Umi) <= maxEdits {
+				groupOf[umi] = group
+				group.Umis = append(group.Umis, umi)
+				break
+			}
+		}
+		if _, ok := groupOf[umi]; !ok {
+			group := &UmiGroup{Umi: umi, Umis: []string{umi}, Records: make([]SeqRecord, 0)}
+			groups = append(groups, group)
+			groupOf[umi] = group
+		}
+	}
+	for i, rec := range records {
+		group := groupOf[umis[i]]
+		group.Records = append(group.Records, rec)
+	}
+	return groups
+}
+
+// Consensus aligns the reads of the group into a graph and sets Seq and Qual to its first consensus.
+// Reads without an alignment to the graph are left out
+func (self *UmiGroup) Consensus(aln *PairwiseAlignmentParameters, mode OrientationMode) (*PoaGraph, error) {
+	if len(self.Records) == 0 {
+		return nil, fmt.Errorf("Consensus: no reads for UMI %v", self.Umi)
+	}
+	g := PoaGraphConstruct()
+	g.AddBaseRecord(self.Records[0])
+	for _, rec := range self.Records[1:] {
+		if mode == OrientKmer {
+			g.updateKmerIndex(orientationKmerSize)
+		}
+		pA := AlignOrientedStringToGraph(g, aln, rec.Seq, rec.Name, mode)
+		if !pA.Aligned() {
+			continue
+		}
+		pA.SetRecord(rec)
+		if err := g.AddSequenceAlignment(pA); err != nil {
+			return nil, err
+		}
+	}
+	self.Seq, self.Qual = g.consensusWithQualities()
+	return g, nil
+}
+
+// ConsensusUmiGroups computes the consensus of the groups with up to threads goroutines, a group per
+// goroutine at a time. Returns the first error
+func ConsensusUmiGroups(groups []*UmiGroup, aln *PairwiseAlignmentParameters, mode OrientationMode, threads int) error {
+	return parallelFor(len(groups), threads, func(i int) error {
+		_, err := groups[i].Consensus(aln, mode)
+		return err
+	})
+}
+
+// the first consensus with a phred quality per base from the sequences going through its node out of
+// the ones covering its column, with one pseudo count on each side
+func (self *PoaGraph) consensusWithQualities() (string, string) {
+	path, bases, _ := self.consensus(nil)
+	columnIndex, _ := self.columnIndex()
+	// the first and last column of each sequence
+	firstCol, lastCol := make([]int, len(self.records)), make([]int, len(self.records))
+	for i := range self.records {
+		ids := self.pathIds(i)
+		if len(ids) == 0 {
+			firstCol[i], lastCol[i] = 1, 0
+			continue
+		}
+		firstCol[i], lastCol[i] = columnIndex[ids[0]], columnIndex[ids[len(ids)-1]]
+	}
+
+	qual := make([]byte, len(path))
+	for i, nodeId := range path {
+		col := columnIndex[nodeId]
+		coverage := 0
+		for j := range self.records {
+			if firstCol[j] <= col && col <= lastCol[j] {
+				coverage += 1
+			}
+		}
+		support := self.nodes[nodeId].labelSet().count()
+		errorRate := float64(coverage-support+1) / float64(coverage+2)
+		q := int(math.Round(-10 * math.Log10(errorRate)))
+		qual[i] = byte(intArrayMin([]int{intArrayMax([]int{q, 0}), maxConsensusQuality}) + 33)
+	}
+	return strings.Join(bases, ""), string(qual)
+}
@@ -0,0 +1,117 @@
+package PoaGo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseUmiSource(t *testing.T) {
+	source, err := ParseUmiSource("seq")
+	assert.Nil(t, err)
+	assert.Equal(t, UmiFromSequence, source)
+	source, err = ParseUmiSource("")
+	assert.Nil(t, err)
+	assert.Equal(t, UmiFromName, source)
+	_, err = ParseUmiSource("tag")
+	assert.NotNil(t, err)
+}
+
+func TestExtractUmi(t *testing.T) {
+	opts := UmiOptionsDefault()
+	umi, rec, ok := ExtractUmi(SeqRecord{Name: "read1_ACGTAC", Seq: "TTTT"}, opts)
+	assert.True(t, ok)
+	assert.Equal(t, "ACGTAC", umi)
+	assert.Equal(t, "TTTT", rec.Seq)
+	_, _, ok = ExtractUmi(SeqRecord{Name: "read1", Seq: "TTTT"}, opts)
+	assert.False(t, ok)
+
+	// the adapter and the UMI are trimmed from the read and its qualities
+	opts.Source = UmiFromSequence
+	opts.Pattern = regexp.MustCompile(`(?i)^GG([ACGT]{4})`)
+	umi, rec, ok = ExtractUmi(SeqRecord{Name: "read1", Seq: "GGacgtTTCA", Qual: "!!####IIII"}, opts)
+	assert.True(t, ok)
+	assert.Equal(t, "ACGT", umi)
+	assert.Equal(t, "TTCA", rec.Seq)
+	assert.Equal(t, "IIII", rec.Qual)
+
+	// without a group the whole match is the UMI
+	opts.Pattern = regexp.MustCompile(`^[ACGT]{3}`)
+	umi, rec, _ = ExtractUmi(SeqRecord{Name: "read1", Seq: "CCATTT"}, opts)
+	assert.Equal(t, "CCA", umi)
+	assert.Equal(t, "TTT", rec.Seq)
+}
+
+func TestGroupByUmi(t *testing.T) {
+	umis := []string{"AAAA", "CCCC", "AAAA", "AAAT", "CCCC", "CCCC", "GGGG"}
+	records := make([]SeqRecord, len(umis))
+	for i := range umis {
+		records[i] = SeqRecord{Name: string(rune('a' + i))}
+	}
+	groups := GroupByUmi(records, umis, 1)
+	assert.Equal(t, 3, len(groups))
+	assert.Equal(t, "CCCC", groups[0].Umi)
+	assert.Equal(t, "AAAA", groups[1].Umi)
+	assert.Equal(t, []string{"AAAA", "AAAT"}, groups[1].Umis)
+	names := func(group *UmiGroup) string {
+		var b strings.Builder
+		for _, rec := range group.Records {
+			b.WriteString(rec.Name)
+		}
+		return b.String()
+	}
+	assert.Equal(t, "bef", names(groups[0]))
+	assert.Equal(t, "acd", names(groups[1]))
+	assert.Equal(t, "g", names(groups[2]))
+
+	// without errors allowed every UMI has its own group
+	assert.Equal(t, 4, len(GroupByUmi(records, umis, 0)))
+}
+
+func TestConsensusUmiGroups(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	truths := []string{randomBases(r, 120), randomBases(r, 120)}
+	records, umis := make([]SeqRecord, 0), make([]string, 0)
+	for i := 0; i < 20; i++ {
+		umi := []string{"ACGTACGT", "TTGGCCAA"}[i%2]
+		if i == 4 {
+			// an error in the UMI
+			umi = "ACGTACGA"
+		}
+		records = append(records, SeqRecord{Name: "read" + string(rune('a'+i)), Seq: mutateSequence(r, truths[i%2], 0.03)})
+		umis = append(umis, umi)
+	}
+	groups := GroupByUmi(records, umis, 1)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, 10, len(groups[0].Records))
+	assert.Equal(t, 10, len(groups[1].Records))
+	assert.Nil(t, ConsensusUmiGroups(groups, aln, OrientNone, 2))
+	// the UMI without errors is the more frequent one
+	assert.Equal(t, "TTGGCCAA", groups[0].Umi)
+	for i, group := range groups {
+		truth := truths[1-i]
+		assert.Equal(t, len(group.Seq), len(group.Qual))
+		assert.True(t, EditDistance(truth, group.Seq) <= 1, "group %v", group.Umi)
+		for _, q := range group.Qual {
+			assert.True(t, q >= '!' && q <= '!'+maxConsensusQuality)
+		}
+	}
+
+	_, err := (&UmiGroup{Umi: "AAAA"}).Consensus(aln, OrientNone)
+	assert.NotNil(t, err)
+}
+
+func TestPoaGraph_consensusWithQualities(t *testing.T) {
+	g := PoaGraphConstruct()
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	g.AddBaseSequence("ACGTACGT", "a", true)
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGTACGT", "b"))
+	g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGAACGT", "c"))
+	seq, qual := g.consensusWithQualities()
+	assert.Equal(t, "ACGTACGT", seq)
+	// 3 of 3 reads: -10 log10(1/5), 2 of 3: -10 log10(2/5)
+	assert.Equal(t, "(((%((((", qual)
+}
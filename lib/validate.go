@@ -0,0 +1,173 @@
+package PoaGo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CycleError : the graph has a cycle, NodeIds go around it and end with the node they start from
+type CycleError struct {
+	NodeIds []int
+	Bases   []string
+}
+
+func (self *CycleError) Error() string {
+	steps := make([]string, len(self.NodeIds))
+	for i, nodeId := range self.NodeIds {
+		steps[i] = fmt.Sprintf("%v (%v)", nodeId, self.Bases[i])
+	}
+	return "cycle in the graph: " + strings.Join(steps, " -> ")
+}
+
+func (self *PoaGraph) cycleError(nodeIds []int) *CycleError {
+	bases := make([]string, len(nodeIds))
+	for i, nodeId := range nodeIds {
+		bases[i] = self.nodes[nodeId].base
+	}
+	return &CycleError{NodeIds: nodeIds, Bases: bases}
+}
+
+// the number of problems Validate lists before giving up
+const maxValidationProblems = 20
+
+// ValidationError : the invariants of the graph that don't hold
+type ValidationError struct {
+	Problems []string
+}
+
+func (self *ValidationError) Error() string {
+	return "invalid graph: " + strings.Join(self.Problems, "; ")
+}
+
+// Validate checks the invariants of the graph: in and out edges are symmetric and carry the same
+// sequences, the node and edge counts match, alignedTo is symmetric, the order is topological when the
+// graph is sorted, there is no cycle and the path of each sequence spells it. Returns a *CycleError for
+// a cycle and a *ValidationError listing the other problems
+func (self *PoaGraph) Validate() error {
+	problems := make([]string, 0)
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	nbNodes, nbEdges := 0, 0
+	for nodeId, node := range self.nodes {
+		if node == nil {
+			continue
+		}
+		nbNodes += 1
+		if node.id != nodeId {
+			problem("node %v has id %v", nodeId, node.id)
+		}
+		for neighbor, edge := range node.outEdges {
+			nbEdges += 1
+			if !checkForNode(self, neighbor) {
+				problem("edge %v -> %v to a missing node", nodeId, neighbor)
+				continue
+			}
+			inEdge, ok := self.nodes[neighbor].inEdges[nodeId]
+			if !ok {
+				problem("edge %v -> %v isn't an in-edge of %v", nodeId, neighbor, neighbor)
+				continue
+			}
+			if fmt.Sprint(edge.SeqIds()) != fmt.Sprint(inEdge.SeqIds()) {
+				problem("edge %v -> %v has sequences %v out and %v in", nodeId, neighbor, edge.SeqIds(), inEdge.SeqIds())
+			}
+			edge.labels.forEach(func(seqId int) {
+				if seqId >= len(self.records) {
+					problem("edge %v -> %v has unknown sequence %v", nodeId, neighbor, seqId)
+				}
+			})
+		}
+		for neighbor := range node.inEdges {
+			if !checkForNode(self, neighbor) {
+				problem("edge %v -> %v from a missing node", neighbor, nodeId)
+			} else if _, ok := self.nodes[neighbor].outEdges[nodeId]; !ok {
+				problem("edge %v -> %v isn't an out-edge of %v", neighbor, nodeId, neighbor)
+			}
+		}
+		for _, other := range node.alignedTo {
+			if other == nodeId || !checkForNode(self, other) {
+				problem("node %v is aligned to %v", nodeId, other)
+			} else if !intArrayContains(self.nodes[other].alignedTo, nodeId) {
+				problem("node %v is aligned to %v but not the other way", nodeId, other)
+			}
+		}
+		if len(problems) >= maxValidationProblems {
+			return &ValidationError{Problems: problems}
+		}
+	}
+	if nbNodes != self.nbNodes {
+		problem("graph has %v nodes, counted %v", self.nbNodes, nbNodes)
+	}
+	if nbEdges != self.nbEdges {
+		problem("graph has %v edges, counted %v", self.nbEdges, nbEdges)
+	}
+	if len(self.nodeList) != nbNodes {
+		problem("%v nodes in the order, %v in the graph", len(self.nodeList), nbNodes)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	if !self.needSort {
+		for rank, nodeId := range self.nodeList {
+			if self.nodes[nodeId].rank != rank {
+				problem("node %v has rank %v at %v in the order", nodeId, self.nodes[nodeId].rank, rank)
+				break
+			}
+		}
+		if !self.testSort() && len(self.nodeList) > 0 {
+			problem("the order isn't topological")
+		}
+	}
+	// a cycle makes the paths below loop
+	if _, cycle := self.dfsOrder(); cycle != nil {
+		return cycle
+	}
+
+	for seqId, rec := range self.records {
+		if rec.Id != seqId {
+			problem("sequence %v has id %v", seqId, rec.Id)
+		}
+		bases := make([]string, 0, len(rec.Seq))
+		for nodeId := rec.start; nodeId >= 0; nodeId = self.nodes[nodeId].NextNode(seqId) {
+			if !checkForNode(self, nodeId) {
+				problem("path of %v goes through missing node %v", rec.Name, nodeId)
+				bases = nil
+				break
+			}
+			bases = append(bases, self.nodes[nodeId].base)
+			nbNext := 0
+			for _, edge := range self.nodes[nodeId].outEdges {
+				if edge.HasLabel(seqId) {
+					nbNext += 1
+				}
+			}
+			if nbNext > 1 {
+				problem("path of %v branches at node %v", rec.Name, nodeId)
+				bases = nil
+				break
+			}
+		}
+		if path := strings.Join(bases, ""); bases != nil && !strings.EqualFold(path, rec.Seq) {
+			problem("path of %v spells %v, not %v", rec.Name, path, rec.Seq)
+		}
+		if len(problems) >= maxValidationProblems {
+			break
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func intArrayContains(arr []int, x int) bool {
+	for _, y := range arr {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package PoaGo
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPoaGraph_TopoSortCycle(t *testing.T) {
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("ACG", "a", true)
+	assert.Nil(t, g.AddEdge(2, 1, 0))
+	err := g.TopoSort()
+	var cycle *CycleError
+	assert.True(t, errors.As(err, &cycle))
+	assert.Equal(t, []int{1, 2, 1}, cycle.NodeIds)
+	assert.Equal(t, []string{"C", "G", "C"}, cycle.Bases)
+	assert.Equal(t, "cycle in the graph: 1 (C) -> 2 (G) -> 1 (C)", err.Error())
+	assert.True(t, g.needSort)
+
+	assert.True(t, errors.As(g.Validate(), &cycle))
+	assert.Panics(t, func() { g.ensureSorted() })
+}
+
+func TestPoaGraph_AddEdgeMissingNode(t *testing.T) {
+	g := PoaGraphConstruct()
+	g.AddBaseSequence("AC", "a", true)
+	assert.Nil(t, g.AddEdge(-1, 0, 0))
+	assert.EqualError(t, g.AddEdge(0, 5, 0), "End node 5 not in graph")
+	assert.EqualError(t, g.AddEdge(7, 0, 0), "Start node 7 not in graph")
+	// the labels of an edge must be sequences of the graph
+	assert.EqualError(t, g.AddEdge(0, 1, 1), "Sequence 1 not in graph")
+	assert.EqualError(t, g.AddEdge(0, 1, -2), "Sequence -2 not in graph")
+	assert.Equal(t, 1, g.NbEdges())
+	assert.Equal(t, []string{"a"}, g.EdgeLabels(0, 1))
+}
+
+func TestPoaGraph_AddSequenceAlignmentErrors(t *testing.T) {
+	g := writerTestGraph()
+	pA := PairwiseAlignmentConstruct([]int{-1, -1}, []int{0, 1}, "GG", "none")
+	assert.EqualError(t, g.AddSequenceAlignment(pA), "AddSequenceAlignment: none has no aligned bases")
+	pA = PairwiseAlignmentConstruct([]int{0, 1}, []int{0, 99}, "GG", "missing")
+	assert.EqualError(t, g.AddSequenceAlignment(pA), "AddSequenceAlignment: missing is aligned to node 99, not in the graph")
+	assert.Equal(t, 2, g.NbSequences())
+	assert.Nil(t, g.Validate())
+}
+
+func TestPoaGraph_AddSequenceAlignmentCycle(t *testing.T) {
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	for _, pA := range []*PairwiseAlignment{
+		// T on node 3 then A on node 0 closes 0 -> 1 -> 2 -> 3 -> 0
+		PairwiseAlignmentConstruct([]int{0, 1}, []int{3, 0}, "TA", "r"),
+		// with ragged ends and a mismatch, the new nodes go too
+		PairwiseAlignmentConstruct([]int{1, 2, 3}, []int{2, 3, 1}, "AGGCC", "r"),
+		// the same node twice
+		PairwiseAlignmentConstruct([]int{0, 1}, []int{1, 1}, "CC", "r"),
+	} {
+		g := PoaGraphConstruct()
+		g.AddBaseSequence("ACGT", "backbone", true)
+		nbNodes, nbEdges := g.NbNodes(), g.NbEdges()
+		err := g.AddSequenceAlignment(pA)
+		var cycle *CycleError
+		assert.True(t, errors.As(err, &cycle), "%v", err)
+
+		// the graph is as it was and can still be used
+		assert.Equal(t, 1, g.NbSequences())
+		assert.Equal(t, nbNodes, g.NbNodes())
+		assert.Equal(t, nbEdges, g.NbEdges())
+		assert.Nil(t, g.Validate())
+		assertOrder(t, g)
+		assert.Nil(t, g.AddSequenceAlignment(AlignStringToGraph(g, aln, "ACGGT", "read")))
+		assert.Equal(t, 2, g.NbSequences())
+		assert.Nil(t, g.Validate())
+		seq, _ := g.Sequence("read")
+		assert.Equal(t, "ACGGT", seq)
+	}
+}
+
+func TestPoaGraph_Validate(t *testing.T) {
+	assert.Nil(t, PoaGraphConstruct().Validate())
+	assert.Nil(t, writerTestGraph().Validate())
+	assert.Nil(t, variantTestGraph().Validate())
+
+	problems := func(g *PoaGraph) string {
+		err := g.Validate()
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			return ""
+		}
+		return strings.Join(invalid.Problems, "; ")
+	}
+
+	g := writerTestGraph()
+	delete(g.nodes[1].inEdges, 0)
+	assert.Contains(t, problems(g), "edge 0 -> 1 isn't an in-edge of 1")
+
+	g = writerTestGraph()
+	g.nodes[1].inEdges[0].AddLabel(5)
+	assert.Contains(t, problems(g), "edge 0 -> 1 has sequences [0 1] out and [0 1 5] in")
+
+	g = writerTestGraph()
+	g.nbEdges += 1
+	assert.Equal(t, "graph has 5 edges, counted 4", problems(g))
+
+	g = writerTestGraph()
+	g.nodes[0].alignedTo = append(g.nodes[0].alignedTo, 2)
+	assert.Equal(t, "node 0 is aligned to 2 but not the other way", problems(g))
+
+	g = writerTestGraph()
+	g.nodeList[0], g.nodeList[1] = g.nodeList[1], g.nodeList[0]
+	assert.Contains(t, problems(g), "the order isn't topological")
+
+	g = writerTestGraph()
+	g.records[1].Seq = "ACGT"
+	assert.Equal(t, "path of new spells ACT, not ACGT", problems(g))
+
+	g = writerTestGraph()
+	g.nodes[1].outEdges[2].AddLabel(1)
+	g.nodes[2].inEdges[1].AddLabel(1)
+	assert.Equal(t, "path of new branches at node 1", problems(g))
+}
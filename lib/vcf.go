@@ -0,0 +1,251 @@
+package PoaGo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type VariantOptions struct {
+	MaxFraction float64 // see AllConsensuses, the first consensus is the reference
+	MinCount    int     // alternative alleles seen in fewer reads are dropped
+}
+
+func VariantOptionsDefault() VariantOptions {
+	return VariantOptions{MaxFraction: DefaultMaxFraction, MinCount: 1}
+}
+
+// Variant : a VCF record, Pos is 1-based on the reference
+type Variant struct {
+	Pos       int
+	Ref       string
+	Alts      []string
+	Depth     int   // number of reads covering the variant
+	AltCounts []int // number of reads with each alternative allele
+	Alleles   []int // allele of each read, 0 for Ref, i for Alts[i-1], -1 if not covered or dropped
+}
+
+// VariantCalls : the variants of the reads against the consensus
+type VariantCalls struct {
+	Reference string // name of the reference, the first consensus
+	Sequence  string // reference sequence
+	Samples   []string
+	sampleOf  []int // sample of each read, reads without a sample are samples of their own
+	Variants  []Variant
+}
+
+// first and last non-gap column of an alignment row, -1 -1 for an empty row
+func rowSpan(row string) (int, int) {
+	first := strings.IndexFunc(row, func(r rune) bool { return r != '-' })
+	last := strings.LastIndexFunc(row, func(r rune) bool { return r != '-' })
+	return first, last
+}
+
+// CallVariants compares the reads to the first consensus column by column. Runs of adjacent columns
+// where a read covering them differs from the consensus become one variant, the alleles are the bases
+// of the reads over the run. Alleles that would be empty (insertions and deletions) are padded with the
+// reference base before the run, or after it at the start of the reference
+func (self *PoaGraph) CallVariants(opts VariantOptions) (*VariantCalls, error) {
+	aw := &collectAlignmentWriter{}
+	alnOpts := AlignmentOptionsDefault()
+	alnOpts.MaxFraction = opts.MaxFraction
+	alnOpts.Consensus = false
+	if err := self.WriteAlignment(aw, alnOpts); err != nil {
+		return nil, err
+	}
+	if len(aw.header.Consensus) == 0 {
+		return nil, fmt.Errorf("no consensus to call variants against")
+	}
+	ref := aw.header.Consensus[0]
+	calls := &VariantCalls{Reference: ref.Name, Sequence: strings.ReplaceAll(ref.Seq, "-", ""), Variants: make([]Variant, 0),
+		Samples: make([]string, 0), sampleOf: make([]int, len(aw.rows))}
+	sampleIdx := make(map[string]int)
+	for i, row := range aw.rows {
+		sample := row.Record.Sample
+		if sample == "" {
+			sample = row.Record.Name
+		}
+		if _, ok := sampleIdx[sample]; !ok {
+			sampleIdx[sample] = len(calls.Samples)
+			calls.Samples = append(calls.Samples, sample)
+		}
+		calls.sampleOf[i] = sampleIdx[sample]
+	}
+
+	spans := make([][2]int, len(aw.rows))
+	for i, row := range aw.rows {
+		first, last := rowSpan(row.Seq)
+		spans[i] = [2]int{first, last}
+	}
+	covers := func(i, start, end int) bool {
+		return spans[i][0] >= 0 && spans[i][0] <= start && spans[i][1] >= end-1
+	}
+
+	// reference position before each column, 1-based position of the last reference base seen
+	refPos := make([]int, aw.header.NbColumns+1)
+	for col := 0; col < aw.header.NbColumns; col++ {
+		refPos[col+1] = refPos[col]
+		if ref.Seq[col] != '-' {
+			refPos[col+1] += 1
+		}
+	}
+
+	differs := func(col int) bool {
+		for i, row := range aw.rows {
+			if covers(i, col, col+1) && !strings.EqualFold(row.Seq[col:col+1], ref.Seq[col:col+1]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for col := 0; col < aw.header.NbColumns; {
+		if !differs(col) {
+			col += 1
+			continue
+		}
+		start := col
+		for col < aw.header.NbColumns && differs(col) {
+			col += 1
+		}
+		if v, ok := variantForRun(aw.rows, ref.Seq, start, col, refPos, covers, opts.MinCount); ok {
+			calls.Variants = append(calls.Variants, v)
+		}
+	}
+
+	return calls, nil
+}
+
+// the variant for the columns start..end-1, false if no alternative allele has enough reads
+func variantForRun(rows []AlignedRow, ref string, start, end int, refPos []int,
+	covers func(i, start, end int) bool, minCount int) (Variant, bool) {
+	allele := func(row string) string {
+		return strings.ToUpper(strings.ReplaceAll(row[start:end], "-", ""))
+	}
+	refAllele := allele(ref)
+	pos := refPos[start] + 1
+
+	counts := make(map[string]int)
+	depth := 0
+	for i, row := range rows {
+		if !covers(i, start, end) {
+			continue
+		}
+		depth += 1
+		if a := allele(row.Seq); a != refAllele {
+			counts[a] += 1
+		}
+	}
+
+	alts := make([]string, 0)
+	for a, n := range counts {
+		if n >= minCount {
+			alts = append(alts, a)
+		}
+	}
+	if len(alts) == 0 {
+		return Variant{}, false
+	}
+	sort.Slice(alts, func(i, j int) bool {
+		if counts[alts[i]] != counts[alts[j]] {
+			return counts[alts[i]] > counts[alts[j]]
+		}
+		return alts[i] < alts[j]
+	})
+	v := Variant{Pos: pos, Depth: depth, AltCounts: make([]int, len(alts)), Alleles: make([]int, len(rows))}
+	alleleIdx := map[string]int{refAllele: 0}
+	for i, a := range alts {
+		v.AltCounts[i] = counts[a]
+		alleleIdx[a] = i + 1
+	}
+	for i, row := range rows {
+		v.Alleles[i] = -1
+		if idx, ok := alleleIdx[allele(row.Seq)]; ok && covers(i, start, end) {
+			v.Alleles[i] = idx
+		}
+	}
+
+	// VCF alleles can't be empty, pad them with a reference base
+	padded := refAllele == ""
+	for _, a := range alts {
+		padded = padded || a == ""
+	}
+	refSeq := strings.ToUpper(strings.ReplaceAll(ref, "-", ""))
+	if padded {
+		if pos > 1 {
+			pos -= 1
+			base := refSeq[pos-1 : pos]
+			refAllele = base + refAllele
+			for i := range alts {
+				alts[i] = base + alts[i]
+			}
+		} else if next := len(refAllele); next < len(refSeq) {
+			base := refSeq[next : next+1]
+			refAllele += base
+			for i := range alts {
+				alts[i] += base
+			}
+		}
+	}
+
+	v.Pos, v.Ref, v.Alts = pos, refAllele, alts
+	return v, true
+}
+
+// genotype of a sample: the most common allele of its reads (haploid), read depth and allele depths
+func (self *VariantCalls) sampleGenotype(v Variant, sample int) string {
+	depths := make([]int, len(v.Alts)+1)
+	depth := 0
+	for i, a := range v.Alleles {
+		if self.sampleOf[i] != sample || a < 0 {
+			continue
+		}
+		depths[a] += 1
+		depth += 1
+	}
+	if depth == 0 {
+		return ".:0:" + strings.TrimSuffix(strings.Repeat("0,", len(depths)), ",")
+	}
+	best := 0
+	ad := make([]string, len(depths))
+	for a, n := range depths {
+		if n > depths[best] {
+			best = a
+		}
+		ad[a] = fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%d:%d:%s", best, depth, strings.Join(ad, ","))
+}
+
+// WriteVCF writes the variants in VCF 4.2 with the depth and allele counts and frequencies in INFO,
+// and a column per sample with its genotype and allele depths
+func (self *VariantCalls) WriteVCF(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "##fileformat=VCFv4.2\n")
+	fmt.Fprint(bw, Program.vcfHeader())
+	fmt.Fprintf(bw, "##contig=<ID=%s,length=%d>\n", self.Reference, len(self.Sequence))
+	fmt.Fprintf(bw, "##INFO=<ID=DP,Number=1,Type=Integer,Description=\"Number of reads covering the variant\">\n")
+	fmt.Fprintf(bw, "##INFO=<ID=AC,Number=A,Type=Integer,Description=\"Number of reads with each alternative allele\">\n")
+	fmt.Fprintf(bw, "##INFO=<ID=AF,Number=A,Type=Float,Description=\"Fraction of the covering reads with each alternative allele\">\n")
+	fmt.Fprintf(bw, "##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype, the most common allele of the reads of the sample\">\n")
+	fmt.Fprintf(bw, "##FORMAT=<ID=DP,Number=1,Type=Integer,Description=\"Number of reads of the sample with one of the alleles\">\n")
+	fmt.Fprintf(bw, "##FORMAT=<ID=AD,Number=R,Type=Integer,Description=\"Number of reads of the sample with each allele\">\n")
+	fmt.Fprintf(bw, "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\t%s\n", strings.Join(self.Samples, "\t"))
+	for _, v := range self.Variants {
+		ac := make([]string, len(v.Alts))
+		af := make([]string, len(v.Alts))
+		for i, n := range v.AltCounts {
+			ac[i] = fmt.Sprint(n)
+			af[i] = fmt.Sprintf("%.3f", fraction(n, v.Depth))
+		}
+		fmt.Fprintf(bw, "%s\t%d\t.\t%s\t%s\t.\tPASS\tDP=%d;AC=%s;AF=%s\tGT:DP:AD", self.Reference, v.Pos, v.Ref,
+			strings.Join(v.Alts, ","), v.Depth, strings.Join(ac, ","), strings.Join(af, ","))
+		for sample := range self.Samples {
+			fmt.Fprintf(bw, "\t%s", self.sampleGenotype(v, sample))
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,59 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func variantTestGraph() *PoaGraph {
+	msa := &MSA{Names: []string{"r1", "r2", "r3", "r4", "r5"},
+		Rows: []string{"ACGTACTGT", "ACGTACTGT", "ATGTACTGT", "ACGTA--GT", "ACGTACTGT"}}
+	g, _ := PoaGraphFromMSA(msa)
+	return g
+}
+
+func TestPoaGraph_CallVariants(t *testing.T) {
+	g := variantTestGraph()
+	calls, err := g.CallVariants(VariantOptionsDefault())
+	assert.Nil(t, err)
+	assert.Equal(t, "ACGTACTGT", calls.Sequence)
+	assert.Equal(t, []Variant{
+		{Pos: 2, Ref: "C", Alts: []string{"T"}, Depth: 5, AltCounts: []int{1}, Alleles: []int{0, 0, 1, 0, 0}},
+		// the deletion is padded with the base before
+		{Pos: 5, Ref: "ACT", Alts: []string{"A"}, Depth: 5, AltCounts: []int{1}, Alleles: []int{0, 0, 0, 1, 0}},
+	}, calls.Variants)
+
+	opts := VariantOptionsDefault()
+	opts.MinCount = 2
+	calls, _ = g.CallVariants(opts)
+	assert.Equal(t, 0, len(calls.Variants))
+}
+
+func TestVariantCalls_WriteVCF(t *testing.T) {
+	calls, _ := variantTestGraph().CallVariants(VariantOptionsDefault())
+	var buf bytes.Buffer
+	assert.Nil(t, calls.WriteVCF(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, "##fileformat=VCFv4.2", lines[0])
+	assert.Contains(t, lines, "##contig=<ID=Consensus0,length=9>")
+	assert.Equal(t, "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tr1\tr2\tr3\tr4\tr5", lines[len(lines)-3])
+	assert.Equal(t, "Consensus0\t5\t.\tACT\tA\t.\tPASS\tDP=5;AC=1;AF=0.200\tGT:DP:AD"+
+		"\t0:1:1,0\t0:1:1,0\t0:1:1,0\t1:1:0,1\t0:1:1,0", lines[len(lines)-1])
+}
+
+func TestVariantCalls_Samples(t *testing.T) {
+	g := variantTestGraph()
+	for i, rec := range g.Records() {
+		rec.Sample = []string{"a", "a", "a", "b", "b"}[i]
+	}
+	calls, _ := g.CallVariants(VariantOptionsDefault())
+	assert.Equal(t, []string{"a", "b"}, calls.Samples)
+	var buf bytes.Buffer
+	assert.Nil(t, calls.WriteVCF(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.True(t, strings.HasSuffix(lines[len(lines)-2], "\tGT:DP:AD\t0:3:2,1\t0:2:2,0"))
+	// ties go to the reference
+	assert.True(t, strings.HasSuffix(lines[len(lines)-1], "\tGT:DP:AD\t0:3:3,0\t0:2:1,1"))
+}
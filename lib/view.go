@@ -0,0 +1,206 @@
+package PoaGo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ColorScheme : residue colors, as "#rrggbb"
+type ColorScheme struct {
+	Name   string
+	colors map[byte]string
+}
+
+func colorGroups(groups map[string]string) map[byte]string {
+	colors := make(map[byte]string)
+	for residues, color := range groups {
+		for i := 0; i < len(residues); i++ {
+			colors[residues[i]] = color
+			colors[residues[i]+'a'-'A'] = color
+		}
+	}
+	return colors
+}
+
+var (
+	// nucleotides
+	NucleotideScheme = ColorScheme{Name: "ansi", colors: colorGroups(map[string]string{
+		"A": "#00c000", "C": "#0060ff", "G": "#ffb000", "TU": "#ff2020"})}
+	// ClustalX amino acid colors
+	ClustalScheme = ColorScheme{Name: "clustal", colors: colorGroups(map[string]string{
+		"AILMFWV": "#80a0f0", "KR": "#f01505", "ED": "#c048c0", "NQST": "#15c015",
+		"C": "#f08080", "G": "#f09048", "P": "#c0c000", "HY": "#15a4a4"})}
+	// Zappo physico-chemical amino acid colors
+	ZappoScheme = ColorScheme{Name: "zappo", colors: colorGroups(map[string]string{
+		"ILVAM": "#ffafaf", "FWY": "#ffc800", "KRH": "#6464ff", "DE": "#ff0000",
+		"STNQ": "#00ff00", "PG": "#ff00ff", "C": "#ffff00"})}
+	NoScheme = ColorScheme{Name: "none", colors: map[byte]string{}}
+)
+
+func ParseColorScheme(name string) (ColorScheme, error) {
+	for _, scheme := range []ColorScheme{NucleotideScheme, ClustalScheme, ZappoScheme, NoScheme} {
+		if strings.ToLower(name) == scheme.Name {
+			return scheme, nil
+		}
+	}
+	return NoScheme, errors.New(fmt.Sprintf("Unknown color scheme %v, should be ansi, clustal, zappo or none", name))
+}
+
+// Color returns the color of a residue, "" if the scheme doesn't color it
+func (self ColorScheme) Color(residue byte) string {
+	return self.colors[residue]
+}
+
+// ANSI 24-bit foreground escape for a "#rrggbb" color
+func ansiColor(color string) string {
+	var r, g, b int
+	fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b)
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b)
+}
+
+const ansiReverse string = "\x1b[7m"
+const ansiReset string = "\x1b[0m"
+
+type ViewOptions struct {
+	Width     int         // number of columns per block
+	Scheme    ColorScheme // residue colors, only used with Color
+	Color     bool        // use ANSI escapes, turn off when the output isn't a terminal
+	Highlight bool        // mark the residues that differ from the first consensus
+	Ruler     bool        // column numbers above each block
+}
+
+func ViewOptionsDefault() ViewOptions {
+	return ViewOptions{Width: 60, Scheme: NucleotideScheme, Color: false, Highlight: true, Ruler: true}
+}
+
+// AlignmentViewer : an AlignmentWriter that renders the alignment for reading in a terminal, in
+// blocks of Width columns. Each line has the row name, the position of the first and last residue of
+// the row in the block, and the residues. With Color residues get the scheme colors and differences
+// from the consensus are in reverse video, without it differences are in lower case
+type AlignmentViewer struct {
+	w         io.Writer
+	opts      ViewOptions
+	nameWidth int
+	reference string
+	rows      []AlignedRow
+	consensus []AlignedRow
+}
+
+func AlignmentViewerConstruct(w io.Writer, opts ViewOptions) *AlignmentViewer {
+	if opts.Width <= 0 {
+		opts.Width = ViewOptionsDefault().Width
+	}
+	return &AlignmentViewer{w: w, opts: opts}
+}
+
+func (self *AlignmentViewer) Begin(header AlignmentHeader) error {
+	self.nameWidth = header.NameWidth
+	self.reference = ""
+	if len(header.Consensus) > 0 {
+		self.reference = header.Consensus[0].Seq
+	}
+	self.rows = make([]AlignedRow, 0, header.NbRows)
+	self.consensus = make([]AlignedRow, 0)
+	return nil
+}
+
+func (self *AlignmentViewer) WriteRow(row AlignedRow) error {
+	self.rows = append(self.rows, row)
+	return nil
+}
+
+func (self *AlignmentViewer) WriteConsensus(row AlignedRow) error {
+	self.consensus = append(self.consensus, row)
+	return nil
+}
+
+// column numbers and tick marks for the columns start..end-1
+func (self *AlignmentViewer) ruler(start, end int) string {
+	numbers := []byte(strings.Repeat(" ", end-start))
+	ticks := []byte(strings.Repeat(" ", end-start))
+	for col := start; col < end; col++ {
+		pos := col + 1
+		switch {
+		case pos%10 == 0:
+			ticks[col-start] = '|'
+			label := fmt.Sprint(pos)
+			if col-start+1 >= len(label) {
+				copy(numbers[col-start+1-len(label):], label)
+			}
+		case pos%5 == 0:
+			ticks[col-start] = '.'
+		}
+	}
+	indent := strings.Repeat(" ", self.nameWidth+9)
+	return indent + strings.TrimRight(string(numbers), " ") + "\n" + indent + strings.TrimRight(string(ticks), " ") + "\n"
+}
+
+// one row of a block, counts is the number of residues of the row before start and is updated
+func (self *AlignmentViewer) renderRow(b *strings.Builder, name, seq string, start, end int, counts *int, isConsensus bool) {
+	first := *counts + 1
+	var line strings.Builder
+	for col := start; col < end; col++ {
+		c := seq[col]
+		if c != '-' {
+			*counts += 1
+		}
+		differs := self.opts.Highlight && !isConsensus && c != '-' && self.reference != "" &&
+			!strings.EqualFold(string(c), self.reference[col:col+1])
+		if !self.opts.Color {
+			if differs {
+				c = strings.ToLower(string(c))[0]
+			}
+			line.WriteByte(c)
+			continue
+		}
+		color := self.opts.Scheme.Color(c)
+		if color == "" && !differs {
+			line.WriteByte(c)
+			continue
+		}
+		if color != "" {
+			line.WriteString(ansiColor(color))
+		}
+		if differs {
+			line.WriteString(ansiReverse)
+		}
+		line.WriteByte(c)
+		line.WriteString(ansiReset)
+	}
+	if *counts < first {
+		// no residues in this block
+		first = *counts
+	}
+	fmt.Fprintf(b, "%-*s %6d  %s  %d\n", self.nameWidth, name, first, line.String(), *counts)
+}
+
+func (self *AlignmentViewer) End() error {
+	nColumns := 0
+	if len(self.rows) > 0 {
+		nColumns = len(self.rows[0].Seq)
+	} else if len(self.consensus) > 0 {
+		nColumns = len(self.consensus[0].Seq)
+	}
+	counts := make([]int, len(self.rows)+len(self.consensus))
+
+	for start := 0; start < nColumns; start += self.opts.Width {
+		end := min(start+self.opts.Width, nColumns)
+		var b strings.Builder
+		if self.opts.Ruler {
+			b.WriteString(self.ruler(start, end))
+		}
+		for i, row := range self.rows {
+			self.renderRow(&b, row.DisplayName(), row.Seq, start, end, &counts[i], false)
+		}
+		for i, row := range self.consensus {
+			self.renderRow(&b, row.Name, row.Seq, start, end, &counts[len(self.rows)+i], true)
+		}
+		b.WriteString("\n")
+		if _, err := io.WriteString(self.w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package PoaGo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func viewRows() []AlignedRow {
+	return []AlignedRow{{Name: "r1", Seq: "ACGT-A"}, {Name: "r2", Seq: "ATGT--"}}
+}
+
+func renderView(t *testing.T, opts ViewOptions) string {
+	var buf bytes.Buffer
+	v := AlignmentViewerConstruct(&buf, opts)
+	consensus := AlignedRow{Name: "Consensus0", Seq: "ACGT-A"}
+	assert.Nil(t, v.Begin(AlignmentHeader{NbColumns: 6, NbRows: 2, NameWidth: 10, Consensus: []AlignedRow{consensus}}))
+	for _, row := range viewRows() {
+		assert.Nil(t, v.WriteRow(row))
+	}
+	assert.Nil(t, v.WriteConsensus(consensus))
+	assert.Nil(t, v.End())
+	return buf.String()
+}
+
+func TestAlignmentViewer_Plain(t *testing.T) {
+	opts := ViewOptionsDefault()
+	opts.Width = 4
+	opts.Ruler = false
+	lines := strings.Split(renderView(t, opts), "\n")
+	assert.Equal(t, "r1              1  ACGT  4", lines[0])
+	// differences from the consensus are lower case
+	assert.Equal(t, "r2              1  AtGT  4", lines[1])
+	assert.Equal(t, "Consensus0      1  ACGT  4", lines[2])
+	assert.Equal(t, "", lines[3])
+	// the counters carry over to the next block
+	assert.Equal(t, "r1              5  -A  5", lines[4])
+	assert.Equal(t, "r2              4  --  4", lines[5])
+	assert.NotContains(t, strings.Join(lines, "\n"), "\x1b[")
+}
+
+func TestAlignmentViewer_Ruler(t *testing.T) {
+	opts := ViewOptionsDefault()
+	opts.Width = 12
+	var buf bytes.Buffer
+	v := AlignmentViewerConstruct(&buf, opts)
+	v.nameWidth = 2
+	assert.Equal(t, strings.Repeat(" ", 11)+"        10\n"+strings.Repeat(" ", 11)+"    .    |\n", v.ruler(0, 12))
+}
+
+func TestAlignmentViewer_Color(t *testing.T) {
+	opts := ViewOptionsDefault()
+	opts.Color = true
+	out := renderView(t, opts)
+	assert.Contains(t, out, ansiColor("#00c000")+"A"+ansiReset)
+	// the T of r2 differs from the consensus C
+	assert.Contains(t, out, ansiColor("#ff2020")+ansiReverse+"T"+ansiReset)
+
+	opts.Scheme = NoScheme
+	opts.Highlight = false
+	assert.NotContains(t, renderView(t, opts), "\x1b[")
+}
+
+func TestParseColorScheme(t *testing.T) {
+	scheme, err := ParseColorScheme("Clustal")
+	assert.Nil(t, err)
+	assert.Equal(t, "#80a0f0", scheme.Color('l'))
+	assert.Equal(t, "", scheme.Color('-'))
+	_, err = ParseColorScheme("rainbow")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,157 @@
+package PoaGo
+
+import (
+	"fmt"
+	"strings"
+)
+
+type WindowOptions struct {
+	WindowLength int // the target is cut into windows of this length
+	MinCoverage  int // windows with fewer read fragments keep the sequence of the target
+	Threads      int // windows aligned at a time
+}
+
+func WindowOptionsDefault() WindowOptions {
+	return WindowOptions{WindowLength: 500, MinCoverage: 3, Threads: 1}
+}
+
+// PolishedTarget : a target sequence with the consensus of each of its windows
+type PolishedTarget struct {
+	Name       string
+	Seq        string
+	NbWindows  int
+	NbPolished int // windows with enough fragments for a consensus
+}
+
+// a piece of the target and the pieces of the reads mapped to it
+type window struct {
+	start, end int
+	fragments  []SeqRecord
+	consensus  string
+}
+
+// the part of the read mapped to target[start:end), in the orientation of the target. The read
+// positions come from the CIGAR, without one they are interpolated between the ends of the mapping,
+// so they are only as close as the indels of the read allow
+func (self ReadMapping) fragment(read SeqRecord, start, end int) (SeqRecord, bool) {
+	tStart, tEnd := intArrayMax([]int{start, self.TargetStart}), intArrayMin([]int{end, self.TargetEnd})
+	if tStart >= tEnd {
+		return SeqRecord{}, false
+	}
+	seq, qual := read.Seq[self.QueryStart:self.QueryEnd], ""
+	if len(read.Qual) == len(read.Seq) {
+		qual = read.Qual[self.QueryStart:self.QueryEnd]
+	}
+	if self.Reverse {
+		seq, qual = ReverseComplement(seq), reverseString(qual)
+	}
+	var first, last int
+	if self.Cigar != nil {
+		offsets := self.queryOffsets([]int{tStart, tEnd})
+		first, last = offsets[0], offsets[1]
+	} else {
+		span := self.TargetEnd - self.TargetStart
+		first = (tStart - self.TargetStart) * len(seq) / span
+		last = (tEnd - self.TargetStart) * len(seq) / span
+	}
+	if first >= last {
+		return SeqRecord{}, false
+	}
+	frag := SeqRecord{Name: read.Name, Seq: seq[first:last], Reverse: self.Reverse}
+	if qual != "" {
+		frag.Qual = qual[first:last]
+	}
+	return frag, true
+}
+
+// the consensus of the window, its piece of the target seeds the graph and counts as one sequence
+func (self *window) polish(backbone SeqRecord, aln *PairwiseAlignmentParameters) error {
+	g := PoaGraphConstruct()
+	g.AddBaseRecord(backbone)
+	for _, frag := range self.fragments {
+		pA := AlignStringToGraph(g, aln, frag.Seq, frag.Name)
+		if !pA.Aligned() {
+			continue
+		}
+		pA.SetRecord(frag)
+		if err := g.AddSequenceAlignment(pA); err != nil {
+			return err
+		}
+	}
+	// the fragments don't all end where the window does, the ends of the consensus supported by fewer
+	// than half of the sequences are trimmed so they don't overlap the next windows
+	_, bases, labels := g.consensus(nil)
+	minSupport := (g.NbSequences() + 1) / 2
+	first, last := 0, len(bases)
+	for first < last && labels[first].count() < minSupport {
+		first += 1
+	}
+	for last > first && labels[last-1].count() < minSupport {
+		last -= 1
+	}
+	self.consensus = strings.Join(bases[first:last], "")
+	return nil
+}
+
+// PolishWindows polishes a draft target with the reads mapped to it, for sequences too long to align
+// the reads to in one go. The target is cut into windows, each read into the fragments mapped to
+// them, each window gets its own graph and the consensus of the windows are joined. Mappings to other
+// targets are ignored, the reads of the others must be in reads
+func PolishWindows(target SeqRecord, reads map[string]SeqRecord, mappings []ReadMapping, aln *PairwiseAlignmentParameters,
+	opts WindowOptions) (*PolishedTarget, error) {
+	if opts.WindowLength < 1 {
+		return nil, fmt.Errorf("PolishWindows: window length should be at least 1, got %v", opts.WindowLength)
+	}
+	windows := make([]*window, 0)
+	for start := 0; start < len(target.Seq); start += opts.WindowLength {
+		end := intArrayMin([]int{start + opts.WindowLength, len(target.Seq)})
+		windows = append(windows, &window{start: start, end: end, fragments: make([]SeqRecord, 0)})
+	}
+
+	for _, m := range mappings {
+		if m.TargetName != target.Name {
+			continue
+		}
+		if m.TargetLen != len(target.Seq) {
+			return nil, fmt.Errorf("PolishWindows: %v is mapped to %v of length %v, the target has length %v",
+				m.QueryName, m.TargetName, m.TargetLen, len(target.Seq))
+		}
+		read, ok := reads[m.QueryName]
+		if !ok {
+			return nil, fmt.Errorf("PolishWindows: no read %v", m.QueryName)
+		}
+		if len(read.Seq) != m.QueryLen {
+			return nil, fmt.Errorf("PolishWindows: %v is mapped with length %v, the read has length %v",
+				m.QueryName, m.QueryLen, len(read.Seq))
+		}
+		for i := m.TargetStart / opts.WindowLength; i < len(windows) && windows[i].start < m.TargetEnd; i++ {
+			if frag, ok := m.fragment(read, windows[i].start, windows[i].end); ok {
+				windows[i].fragments = append(windows[i].fragments, frag)
+			}
+		}
+	}
+
+	err := parallelFor(len(windows), opts.Threads, func(i int) error {
+		w := windows[i]
+		backbone := SeqRecord{Name: target.Name, Seq: target.Seq[w.start:w.end]}
+		if len(w.fragments) < opts.MinCoverage {
+			w.consensus = backbone.Seq
+			return nil
+		}
+		return w.polish(backbone, aln)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PolishedTarget{Name: target.Name, NbWindows: len(windows)}
+	consensus := make([]string, len(windows))
+	for i, w := range windows {
+		if len(w.fragments) >= opts.MinCoverage {
+			result.NbPolished += 1
+		}
+		consensus[i] = w.consensus
+	}
+	result.Seq = strings.Join(consensus, "")
+	return result, nil
+}
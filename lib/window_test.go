@@ -0,0 +1,143 @@
+package PoaGo
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestReadMapping_fragment(t *testing.T) {
+	read := SeqRecord{Name: "read", Seq: "TTACGTACGTAA", Qual: "!!ABCDEFGH!!"}
+	m := ReadMapping{QueryName: "read", QueryLen: 12, QueryStart: 2, QueryEnd: 10, TargetName: "ctg", TargetLen: 20,
+		TargetStart: 4, TargetEnd: 12}
+	frag, ok := m.fragment(read, 0, 8)
+	assert.True(t, ok)
+	assert.Equal(t, SeqRecord{Name: "read", Seq: "ACGT", Qual: "ABCD"}, frag)
+	frag, _ = m.fragment(read, 8, 16)
+	assert.Equal(t, "ACGT", frag.Seq)
+	_, ok = m.fragment(read, 12, 20)
+	assert.False(t, ok)
+
+	// on the reverse strand the fragments are in the orientation of the target
+	m.Reverse = true
+	frag, _ = m.fragment(read, 0, 6)
+	assert.Equal(t, SeqRecord{Name: "read", Seq: "AC", Qual: "HG", Reverse: true}, frag)
+}
+
+func TestPolishWindows(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	truth := randomBases(r, 1000)
+	draft := SeqRecord{Name: "ctg", Seq: mutateSequence(r, truth, 0.03)}
+
+	// reads covering the draft from end to end, every other one on the reverse strand
+	reads := make(map[string]SeqRecord)
+	mappings := make([]ReadMapping, 0)
+	for i := 0; i < 10; i++ {
+		name := "read" + string(rune('a'+i))
+		seq := mutateSequence(r, truth, 0.05)
+		m := ReadMapping{QueryName: name, QueryLen: len(seq), QueryEnd: len(seq), TargetName: "ctg",
+			TargetLen: len(draft.Seq), TargetEnd: len(draft.Seq), Reverse: i%2 == 1}
+		if m.Reverse {
+			seq = ReverseComplement(seq)
+		}
+		reads[name] = SeqRecord{Name: name, Seq: seq}
+		mappings = append(mappings, m)
+	}
+	// a read mapped to another target is left out
+	mappings = append(mappings, ReadMapping{QueryName: "other", TargetName: "ctg2"})
+
+	opts := WindowOptionsDefault()
+	opts.WindowLength, opts.Threads = 200, 3
+	polished, err := PolishWindows(draft, reads, mappings, aln, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "ctg", polished.Name)
+	assert.Equal(t, 5, polished.NbWindows)
+	assert.Equal(t, 5, polished.NbPolished)
+	assert.True(t, EditDistance(truth, polished.Seq) < EditDistance(truth, draft.Seq))
+
+	// without enough reads the draft is kept
+	opts.MinCoverage = 11
+	polished, _ = PolishWindows(draft, reads, mappings, aln, opts)
+	assert.Equal(t, 0, polished.NbPolished)
+	assert.Equal(t, draft.Seq, polished.Seq)
+
+	_, err = PolishWindows(draft, map[string]SeqRecord{}, mappings, aln, opts)
+	assert.NotNil(t, err)
+	opts.WindowLength = 0
+	_, err = PolishWindows(draft, reads, mappings, aln, opts)
+	assert.NotNil(t, err)
+}
+
+// the mappings of the reads to the draft as SAM lines, the reads are aligned to the draft in a graph
+func mapToDraft(draft SeqRecord, reads []SeqRecord, reverse []bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "@SQ\tSN:%s\tLN:%d\n", draft.Name, len(draft.Seq))
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	for i, read := range reads {
+		g := PoaGraphConstruct()
+		g.AddBaseSequence(draft.Seq, draft.Name, true)
+		g.AddSequenceAlignment(AlignStringToGraph(g, aln, read.Seq, read.Name))
+		_, rows := g.GenerateAlignmentStrings()
+		pos, cigar, seq := SamRecordFields(rows[0], rows[1])
+		flag := 0
+		if reverse[i] {
+			flag = samReverse
+		}
+		fmt.Fprintf(&b, "%s\t%d\t%s\t%d\t60\t%s\t*\t0\t0\t%s\t*\n", read.Name, flag, draft.Name, pos, cigar, seq)
+	}
+	return b.String()
+}
+
+func TestPolishWindows_Cigar(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	aln := PairwiseAlignmentParametersConstruct(4.0, -2.0, -4.0, -2.0)
+	truth := randomBases(r, 600)
+	draft := SeqRecord{Name: "ctg", Seq: mutateSequence(r, truth, 0.03)}
+	reads, reverse := make([]SeqRecord, 0), make([]bool, 0)
+	for i := 0; i < 10; i++ {
+		reads = append(reads, SeqRecord{Name: "read" + string(rune('a'+i)), Seq: mutateSequence(r, truth, 0.05)})
+		reverse = append(reverse, i%2 == 1)
+	}
+	mappings, samReads, err := ReadSAM(strings.NewReader(mapToDraft(draft, reads, reverse)))
+	assert.Nil(t, err)
+	readMap := make(map[string]SeqRecord)
+	for i, read := range samReads {
+		assert.Equal(t, reads[i].Name, read.Name)
+		if reverse[i] {
+			assert.Equal(t, ReverseComplement(reads[i].Seq), read.Seq)
+		}
+		readMap[read.Name] = read
+	}
+
+	// the fragments of a read follow each other without overlaps or gaps
+	for _, m := range mappings {
+		joined := ""
+		for start := 0; start < len(draft.Seq); start += 100 {
+			if frag, ok := m.fragment(readMap[m.QueryName], start, start+100); ok {
+				joined += frag.Seq
+			}
+		}
+		seq := readMap[m.QueryName].Seq[m.QueryStart:m.QueryEnd]
+		if m.Reverse {
+			seq = ReverseComplement(seq)
+		}
+		assert.Equal(t, seq, joined)
+	}
+
+	opts := WindowOptionsDefault()
+	opts.WindowLength, opts.Threads = 100, 3
+	polished, err := PolishWindows(draft, readMap, mappings, aln, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, polished.NbWindows, polished.NbPolished)
+	assert.True(t, EditDistance(truth, polished.Seq) <= 2, "%v edits", EditDistance(truth, polished.Seq))
+
+	// the same mappings without their CIGAR are interpolated
+	for i := range mappings {
+		mappings[i].Cigar = nil
+	}
+	interpolated, _ := PolishWindows(draft, readMap, mappings, aln, opts)
+	assert.True(t, EditDistance(truth, polished.Seq) <= EditDistance(truth, interpolated.Seq))
+}